@@ -0,0 +1,231 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/namnv2496/go-ide-pair/internal/auth"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/executor/socket"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// claimsKey is where requireToken leaves the verified claims in the context.
+const claimsKey = "claims"
+
+// oidcCookie holds the state and nonce of an OIDC login in progress.
+const oidcCookie = "oidc_login"
+
+// requireToken rejects requests without a valid bearer token of one of kinds.
+func requireToken(kinds ...auth.Kind) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, err := auth.FromRequest(ctx.Request, kinds...)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Set(claimsKey, claims)
+	}
+}
+
+// requireRoom rejects requests without a room token for the :id room.
+func requireRoom() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, err := auth.FromRequest(ctx.Request, auth.RoomToken)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if claims.Room != ctx.Param("id") {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "the token is for another room"})
+			return
+		}
+		if kicked(claims) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "you were removed from this room"})
+			return
+		}
+		ctx.Set(claimsKey, claims)
+	}
+}
+
+// requireAdmin rejects requests without the session token of one of
+// configs.AdminUsers.
+func requireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, err := auth.FromRequest(ctx.Request, auth.SessionToken)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if !slices.Contains(configs.GetInstance().AdminUsers, claims.Subject) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only admins can edit problems"})
+			return
+		}
+		ctx.Set(claimsKey, claims)
+	}
+}
+
+// kicked reports whether the holder of a room token was removed from its
+// room.
+func kicked(claims auth.Claims) bool {
+	return claims.Role != model.Interviewer && socket.IsKicked(claims.Room, claims.Subject)
+}
+
+// claimsOf returns the claims requireToken or requireRoom verified.
+func claimsOf(ctx *gin.Context) auth.Claims {
+	return ctx.MustGet(claimsKey).(auth.Claims)
+}
+
+// credentials is the body of POST /auth/register and /auth/login.
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginResponse carries the session token of a successful login.
+type loginResponse struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// registerHandler creates a local account and logs it in.
+func registerHandler(ctx *gin.Context) {
+	var req credentials
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if err := model.ValidateUsername(req.Username); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password: " + err.Error()})
+		return
+	}
+	user := model.User{Username: req.Username, PasswordHash: hash, Provider: "local", CreatedAt: time.Now().UnixMilli()}
+	err = store.GetInstance().CreateUser(user)
+	if errors.Is(err, store.ErrExists) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "username is taken"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save user: " + err.Error()})
+		return
+	}
+	log.Printf("Registered user %s", user.Username)
+	respondWithSession(ctx, http.StatusCreated, user.Username)
+}
+
+// loginHandler checks a local account's password.
+func loginHandler(ctx *gin.Context) {
+	var req credentials
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	user, err := store.GetInstance().GetUser(req.Username)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user: " + err.Error()})
+		return
+	}
+	if err != nil || user.PasswordHash == "" || !auth.CheckPassword(user.PasswordHash, req.Password) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "wrong username or password"})
+		return
+	}
+	respondWithSession(ctx, http.StatusOK, user.Username)
+}
+
+func respondWithSession(ctx *gin.Context, status int, username string) {
+	token, err := auth.NewSessionToken(username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign token: " + err.Error()})
+		return
+	}
+	ctx.JSON(status, loginResponse{Username: username, Token: token})
+}
+
+// authConfigHandler tells the web client which logins are available.
+func authConfigHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"oidc": auth.OIDCEnabled()})
+}
+
+// oidcLoginHandler sends the browser to the OIDC provider, remembering the
+// login's state and nonce in a cookie.
+func oidcLoginHandler(ctx *gin.Context) {
+	if !auth.OIDCEnabled() {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "OIDC login is not configured"})
+		return
+	}
+	authURL, state, nonce, err := auth.OIDCLogin(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcCookie, state+"."+nonce, 600, "/auth/oidc", "", false, true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// oidcCallbackHandler finishes an OIDC login: the account is created on the
+// first one, and the session token goes to configs.OIDCReturnURL.
+func oidcCallbackHandler(ctx *gin.Context) {
+	cookie, err := ctx.Cookie(oidcCookie)
+	state, nonce, _ := strings.Cut(cookie, ".")
+	if err != nil || state == "" || ctx.Query("state") != state {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "the login has expired or was started elsewhere"})
+		return
+	}
+	ctx.SetCookie(oidcCookie, "", -1, "/auth/oidc", "", false, true)
+	if reason := ctx.Query("error"); reason != "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "the provider refused the login: " + reason})
+		return
+	}
+	identity, err := auth.OIDCExchange(ctx.Request.Context(), ctx.Query("code"), nonce)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	userStore := store.GetInstance()
+	user := model.User{Username: identity.Username, Provider: "oidc", Subject: identity.Subject, CreatedAt: time.Now().UnixMilli()}
+	err = userStore.CreateUser(user)
+	if errors.Is(err, store.ErrExists) {
+		user, err = userStore.GetUser(identity.Username)
+		if err == nil && (user.Provider != "oidc" || user.Subject != identity.Subject) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "username " + identity.Username + " belongs to another account"})
+			return
+		}
+	} else if err == nil {
+		log.Printf("Registered user %s through OIDC", user.Username)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save user: " + err.Error()})
+		return
+	}
+
+	returnURL := configs.GetInstance().OIDCReturnURL
+	if returnURL == "" {
+		respondWithSession(ctx, http.StatusOK, user.Username)
+		return
+	}
+	token, err := auth.NewSessionToken(user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign token: " + err.Error()})
+		return
+	}
+	fragment := url.Values{"username": {user.Username}, "token": {token}}
+	ctx.Redirect(http.StatusFound, returnURL+"#"+fragment.Encode())
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// languagesHandler lists the languages of the registry with their default
+// limits and compiler options, in the order the editor should offer them.
+func languagesHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, configs.GetLanguages())
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// problemView is what candidates may see of a problem: everything except the
+// hidden tests and the checker.
+type problemView struct {
+	ID              string                               `json:"id"`
+	Title           string                               `json:"title"`
+	Statement       string                               `json:"statement"`
+	StarterCode     map[model.ProgrammingLanguage]string `json:"starterCode"`
+	SampleTests     []model.ProblemTest                  `json:"sampleTests"`
+	HiddenTestCount int                                  `json:"hiddenTestCount"`
+	TimeLimit       int                                  `json:"timeLimit"`
+	MemoryLimit     int                                  `json:"memoryLimit"`
+	Comparator      model.Comparator                     `json:"comparator,omitempty"`
+	Tolerance       float64                              `json:"tolerance,omitempty"`
+	CreatedAt       int64                                `json:"createdAt"`
+	UpdatedAt       int64                                `json:"updatedAt"`
+}
+
+func newProblemView(p model.Problem) problemView {
+	return problemView{
+		ID:              p.ID,
+		Title:           p.Title,
+		Statement:       p.Statement,
+		StarterCode:     p.StarterCode,
+		SampleTests:     p.SampleTests,
+		HiddenTestCount: len(p.HiddenTests),
+		TimeLimit:       p.TimeLimit,
+		MemoryLimit:     p.MemoryLimit,
+		Comparator:      p.Comparator,
+		Tolerance:       p.Tolerance,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
+// validateProblem returns a description of what is wrong with p, or "".
+func validateProblem(p model.Problem) string {
+	switch {
+	case strings.TrimSpace(p.Title) == "":
+		return "title is required"
+	case len(p.Title) > 128:
+		return "title exceeds 128 character limit"
+	case len(p.Statement) > 65536:
+		return "statement exceeds 65536 character limit"
+	case p.TimeLimit < 0 || p.TimeLimit > 10000:
+		return "timeLimit must be between 0 and 10000 ms"
+	case p.MemoryLimit < 0 || p.MemoryLimit > 1024:
+		return "memoryLimit must be between 0 and 1024 MB"
+	case !p.Comparator.Valid():
+		return "unknown comparator: " + string(p.Comparator)
+	case p.Comparator == model.CustomComparator && p.Checker == "":
+		return "checker is required for the custom comparator"
+	}
+	for _, code := range p.StarterCode {
+		if len(code) > 8192 {
+			return "starter code exceeds 8192 character limit"
+		}
+	}
+	for _, test := range append(append([]model.ProblemTest{}, p.SampleTests...), p.HiddenTests...) {
+		// Test cases are run one input line each, and blank lines are
+		// skipped, which would pair later cases with the wrong output.
+		input := strings.TrimSpace(test.Input)
+		if strings.Contains(input, "\n") {
+			return "test input must be a single line"
+		}
+		if input == "" {
+			return "test input must not be empty"
+		}
+	}
+	return ""
+}
+
+// createProblemHandler adds a problem to the bank.
+func createProblemHandler(ctx *gin.Context) {
+	var problem model.Problem
+	if err := ctx.ShouldBindJSON(&problem); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if msg := validateProblem(problem); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	problem.ID = uuid.NewString()
+	problem.CreatedAt = time.Now().UnixMilli()
+	problem.UpdatedAt = problem.CreatedAt
+	if err := store.GetInstance().SaveProblem(problem); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save problem: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, newProblemView(problem))
+}
+
+// listProblemsHandler returns every problem in the bank.
+func listProblemsHandler(ctx *gin.Context) {
+	problems, err := store.GetInstance().ListProblems()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load problems: " + err.Error()})
+		return
+	}
+	views := make([]problemView, 0, len(problems))
+	for _, p := range problems {
+		views = append(views, newProblemView(p))
+	}
+	ctx.JSON(http.StatusOK, views)
+}
+
+// getProblemHandler returns a problem without its hidden tests.
+func getProblemHandler(ctx *gin.Context) {
+	problem, ok := loadProblem(ctx, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newProblemView(problem))
+}
+
+// updateProblemHandler replaces a problem, keeping its ID and creation time.
+func updateProblemHandler(ctx *gin.Context) {
+	existing, ok := loadProblem(ctx, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	var problem model.Problem
+	if err := ctx.ShouldBindJSON(&problem); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if msg := validateProblem(problem); msg != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	problem.ID = existing.ID
+	problem.CreatedAt = existing.CreatedAt
+	problem.UpdatedAt = time.Now().UnixMilli()
+	if err := store.GetInstance().SaveProblem(problem); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save problem: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, newProblemView(problem))
+}
+
+// deleteProblemHandler removes a problem from the bank. Rooms bound to it
+// keep their code but can no longer be graded.
+func deleteProblemHandler(ctx *gin.Context) {
+	err := store.GetInstance().DeleteProblem(ctx.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "problem not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete problem: " + err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// loadProblem fetches a problem, writing the error response itself when it
+// can't.
+func loadProblem(ctx *gin.Context, id string) (model.Problem, bool) {
+	problem, err := store.GetInstance().GetProblem(id)
+	if errors.Is(err, store.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "problem not found"})
+		return problem, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load problem: " + err.Error()})
+		return problem, false
+	}
+	return problem, true
+}
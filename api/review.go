@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/namnv2496/go-ide-pair/internal/executor/socket"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/report"
+)
+
+// scorecardExport is the JSON export of a room's review.
+type scorecardExport struct {
+	RoomID    string          `json:"roomId"`
+	Notes     string          `json:"notes"`
+	Scorecard model.Scorecard `json:"scorecard"`
+	UpdatedAt int64           `json:"updatedAt"`
+}
+
+// scorecardHandler downloads the interviewers' notes and scorecard of a
+// room, as JSON or, with ?format=pdf, as a PDF. Only interviewers see them.
+func scorecardHandler(ctx *gin.Context) {
+	if claimsOf(ctx).Role != model.Interviewer {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only interviewers can read the scorecard"})
+		return
+	}
+	roomID := ctx.Param("id")
+	review, _ := socket.GetRoomReview(roomID)
+
+	switch ctx.DefaultQuery("format", "json") {
+	case "json":
+		ctx.Header("Content-Disposition", `attachment; filename="scorecard-`+roomID+`.json"`)
+		ctx.JSON(http.StatusOK, scorecardExport{
+			RoomID:    roomID,
+			Notes:     review.Notes,
+			Scorecard: review.Scorecard,
+			UpdatedAt: review.UpdatedAt,
+		})
+	case "pdf":
+		ctx.Header("Content-Disposition", `attachment; filename="scorecard-`+roomID+`.pdf"`)
+		ctx.Data(http.StatusOK, "application/pdf", report.ScorecardPDF(roomID, review))
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or pdf"})
+	}
+}
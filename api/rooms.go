@@ -0,0 +1,211 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/namnv2496/go-ide-pair/internal/auth"
+	"github.com/namnv2496/go-ide-pair/internal/executor/socket"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// roomAccess is the response of creating or joining a room: the room token
+// to enter it with.
+type roomAccess struct {
+	RoomID string     `json:"roomId"`
+	Role   model.Role `json:"role"`
+	Token  string     `json:"token"`
+}
+
+// createRoomHandler opens a new room with the caller as its interviewer.
+func createRoomHandler(ctx *gin.Context) {
+	username := claimsOf(ctx).Subject
+	respondWithRoom(ctx, http.StatusCreated, username, uuid.NewString(), model.Interviewer)
+}
+
+func respondWithRoom(ctx *gin.Context, status int, username, roomID string, role model.Role) {
+	token, err := auth.NewRoomToken(username, roomID, role)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign token: " + err.Error()})
+		return
+	}
+	ctx.JSON(status, roomAccess{RoomID: roomID, Role: role, Token: token})
+}
+
+// inviteRequest is the body of POST /rooms/:id/invites.
+type inviteRequest struct {
+	Role model.Role `json:"role"`
+	// ExpiresIn is how long the invite can be redeemed, in minutes; 0 means
+	// a day.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+// maxInviteTTL is the longest an invite stays valid, in minutes.
+const maxInviteTTL = 7 * 24 * 60
+
+// createInviteHandler signs an invite into the room. Only interviewers
+// invite.
+func createInviteHandler(ctx *gin.Context) {
+	claims := claimsOf(ctx)
+	if claims.Role != model.Interviewer {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only interviewers can invite"})
+		return
+	}
+	var req inviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if !req.Role.Valid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown role: " + string(req.Role)})
+		return
+	}
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = 24 * 60
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxInviteTTL {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "expiresIn must be between 0 and 10080 minutes"})
+		return
+	}
+	expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Minute)
+	token, err := auth.NewInviteToken(claims.Room, req.Role, expiresAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign token: " + err.Error()})
+		return
+	}
+	log.Printf("Room %s: %s invited a %s until %s", claims.Room, claims.Subject, req.Role, expiresAt.Format(time.RFC3339))
+	ctx.JSON(http.StatusCreated, gin.H{"token": token, "role": req.Role, "expiresAt": expiresAt.UnixMilli()})
+}
+
+// redeemInviteRequest is the body of POST /invites/redeem.
+type redeemInviteRequest struct {
+	Invite string `json:"invite"`
+}
+
+// redeemInviteHandler lets the logged-in caller into the room of an invite,
+// with the invite's role.
+func redeemInviteHandler(ctx *gin.Context) {
+	var req redeemInviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	invite, err := auth.Verify(req.Invite, auth.InviteToken)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "invite: " + err.Error()})
+		return
+	}
+	username := claimsOf(ctx).Subject
+	log.Printf("Room %s: %s joined as %s", invite.Room, username, invite.Role)
+	respondWithRoom(ctx, http.StatusOK, username, invite.Room, invite.Role)
+}
+
+// roomEventsHandler returns the recorded timeline of a room.
+func roomEventsHandler(ctx *gin.Context) {
+	events, err := store.GetInstance().ListEvents(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load events: " + err.Error()})
+		return
+	}
+	if events == nil {
+		events = []model.RoomEvent{}
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// bindProblemRequest is the body of PUT /rooms/:id/problem.
+type bindProblemRequest struct {
+	ProblemID string                    `json:"problemId"`
+	Language  model.ProgrammingLanguage `json:"language"`
+}
+
+// bindProblemHandler binds a room to a problem and seeds its editor with the
+// problem's starter code for the chosen language. Only interviewers choose
+// the problem.
+func bindProblemHandler(ctx *gin.Context) {
+	if claimsOf(ctx).Role != model.Interviewer {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only interviewers can change the problem"})
+		return
+	}
+	var req bindProblemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+	if _, err := worker.GetExecutor(req.Language); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	problem, ok := loadProblem(ctx, req.ProblemID)
+	if !ok {
+		return
+	}
+	socket.BindProblem(ctx.Param("id"), problem.ID, req.Language, problem.StarterCode[req.Language])
+	ctx.JSON(http.StatusOK, newProblemView(problem))
+}
+
+// gradeHandler runs the room's current files against the hidden tests of its
+// problem. Like /submit it returns the queued execution; the result, polled
+// from GET /executions/:id, shows verdicts but never the tests themselves.
+// Observers only watch, as with /submit.
+func gradeHandler(ctx *gin.Context) {
+	if claimsOf(ctx).Role == model.Observer {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "observers cannot grade the room's code"})
+		return
+	}
+	snapshot, ok := socket.GetRoomSnapshot(ctx.Param("id"))
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "room not found"})
+		return
+	}
+	if snapshot.ProblemID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "room is not bound to a problem"})
+		return
+	}
+	problem, ok := loadProblem(ctx, snapshot.ProblemID)
+	if !ok {
+		return
+	}
+	if len(problem.HiddenTests) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "problem has no hidden tests"})
+		return
+	}
+	if _, err := worker.GetExecutor(snapshot.Language); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inputs := make([]string, len(problem.HiddenTests))
+	expected := make([]*string, len(problem.HiddenTests))
+	for i, test := range problem.HiddenTests {
+		inputs[i] = strings.TrimSpace(test.Input)
+		expected[i] = &test.Expected
+		if inputs[i] == "" {
+			// Saved before empty inputs were rejected: the blank line would
+			// be skipped and every later case judged against the wrong output.
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "the problem has a hidden test with no input; edit it first"})
+			return
+		}
+	}
+	source := model.SourceCode{
+		Name:        "grade",
+		Language:    snapshot.Language,
+		Files:       snapshot.Files,
+		Entrypoint:  snapshot.Entrypoint,
+		Input:       strings.Join(inputs, "\n"),
+		Expected:    expected,
+		Comparator:  problem.Comparator,
+		Tolerance:   problem.Tolerance,
+		Checker:     problem.Checker,
+		TimeLimit:   problem.TimeLimit,
+		MemoryLimit: problem.MemoryLimit,
+	}
+	// No room ID: the hidden tests' output must not be streamed to the room.
+	queueExecution(ctx, source, "", true)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/namnv2496/go-ide-pair/internal/auth"
 )
 
 func NewServer() {
@@ -14,12 +15,33 @@ func NewServer() {
 	route.Use(cors.New(cors.Config{
 		// AllowOrigins:  allowedOrigins,
 		AllowAllOrigins: true,
-		AllowMethods:    []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:    []string{"Origin", "Content-Type", "Accept"},
+		AllowMethods:    []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:    []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:   []string{"Content-Length"},
 		MaxAge:          12 * time.Hour,
 	}))
 
-	route.POST("/submit", submitHandler)
+	route.POST("/auth/register", registerHandler)
+	route.POST("/auth/login", loginHandler)
+	route.GET("/auth/config", authConfigHandler)
+	route.GET("/auth/oidc/login", oidcLoginHandler)
+	route.GET("/auth/oidc/callback", oidcCallbackHandler)
+
+	session := requireToken(auth.SessionToken)
+	route.GET("/languages", languagesHandler)
+	route.POST("/submit", requireToken(auth.SessionToken, auth.RoomToken), submitHandler)
+	route.GET("/executions/:id", requireToken(auth.SessionToken, auth.RoomToken), executionHandler)
+	route.POST("/rooms", session, createRoomHandler)
+	route.POST("/invites/redeem", session, redeemInviteHandler)
+	route.POST("/rooms/:id/invites", requireRoom(), createInviteHandler)
+	route.GET("/rooms/:id/events", requireRoom(), roomEventsHandler)
+	route.PUT("/rooms/:id/problem", requireRoom(), bindProblemHandler)
+	route.POST("/rooms/:id/grade", requireRoom(), gradeHandler)
+	route.GET("/rooms/:id/scorecard", requireRoom(), scorecardHandler)
+	route.POST("/problems", requireAdmin(), createProblemHandler)
+	route.GET("/problems", listProblemsHandler)
+	route.GET("/problems/:id", getProblemHandler)
+	route.PUT("/problems/:id", requireAdmin(), updateProblemHandler)
+	route.DELETE("/problems/:id", requireAdmin(), deleteProblemHandler)
 	route.Run(":8080")
 }
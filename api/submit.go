@@ -1,41 +1,137 @@
 package api
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	java_job_executor "github.com/namnv2496/go-ide-pair/internal/executor/worker/java_worker"
-	python3_job_executor "github.com/namnv2496/go-ide-pair/internal/executor/worker/python3_worker"
+	"github.com/google/uuid"
+	"github.com/namnv2496/go-ide-pair/internal/auth"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker"
 	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
 )
 
+// submitRequest is the body of POST /submit. RoomID is optional; when set,
+// the program's output is streamed live to everyone in that room.
+type submitRequest struct {
+	model.SourceCode
+	RoomID string `json:"roomId"`
+}
+
+// submitHandler validates the source, queues it for execution and returns the
+// execution ID immediately; poll GET /executions/:id for the result.
 func submitHandler(ctx *gin.Context) {
-	var req model.SourceCode
+	var req submitRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
 		return
 	}
 
-	if len(req.Content) == 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+	// Running in a room streams to it, so it takes a token for that room,
+	// and observers only watch.
+	if claims := claimsOf(ctx); req.RoomID != "" && (claims.Kind != auth.RoomToken || claims.Room != req.RoomID) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "a token for room " + req.RoomID + " is required"})
+		return
+	} else if req.RoomID != "" && (claims.Role == model.Observer || kicked(claims)) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "you cannot run code in this room"})
+		return
+	}
+	if len(req.Content) == 0 && len(req.Files) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "content or files is required"})
 		return
 	}
 	if len(req.Content) > 8192 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "content exceeds 8192 character limit"})
 		return
 	}
+	if err := req.ValidateFiles(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if len(req.Input) > 8192 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "input exceeds 8192 character limit"})
 		return
 	}
+	if !req.Comparator.Valid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown comparator: " + string(req.Comparator)})
+		return
+	}
+	if req.Comparator == model.CustomComparator && req.Checker == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "checker is required for the custom comparator"})
+		return
+	}
+	if len(req.Checker) > 8192 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "checker exceeds 8192 character limit"})
+		return
+	}
+	if req.TimeLimit < 0 || req.TimeLimit > 10000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "timeLimit must be between 0 and 10000 ms"})
+		return
+	}
+	if req.MemoryLimit < 0 || req.MemoryLimit > 1024 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "memoryLimit must be between 0 and 1024 MB"})
+		return
+	}
+	if req.Interactive && req.RoomID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "interactive runs need a roomId to take input from"})
+		return
+	}
+	if err := worker.ValidateSource(req.SourceCode); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	queueExecution(ctx, req.SourceCode, req.RoomID, false)
+}
 
-	switch req.Language {
-	case model.Python3:
-		ctx.JSON(http.StatusOK, python3_job_executor.GetInstance().Execute(req))
-	case model.Java:
-		ctx.JSON(http.StatusOK, java_job_executor.GetInstance().Execute(req))
-	default:
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported language: %d", req.Language)})
+// queueExecution saves a new execution of source, queues it and responds
+// with 202 and the execution. roomID, when set, receives the live output;
+// hidden executions are only ever returned redacted. The caller, and their
+// room when they used a room token, may read it.
+func queueExecution(ctx *gin.Context, source model.SourceCode, roomID string, hidden bool) {
+	claims := claimsOf(ctx)
+	exec := model.Execution{
+		ID:        uuid.NewString(),
+		Timestamp: time.Now().UnixMilli(),
+		Status:    model.NotExecuted,
+		Hidden:    hidden,
+		Owner:     claims.Subject,
+	}
+	if claims.Kind == auth.RoomToken {
+		exec.RoomID = claims.Room
+	}
+	executionStore := store.GetInstance()
+	if err := executionStore.SaveExecution(exec); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save execution: " + err.Error()})
+		return
+	}
+	if err := worker.GetInstance().Enqueue(worker.Job{ID: exec.ID, RoomID: roomID, Source: source}); err != nil {
+		exec.Status = model.RuntimeError
+		exec.Stderr = err.Error()
+		executionStore.SaveExecution(exec)
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusAccepted, exec)
+}
+
+// executionHandler returns the current state of an execution to its owner
+// or to their room.
+func executionHandler(ctx *gin.Context) {
+	exec, err := store.GetInstance().GetExecution(ctx.Param("id"))
+	claims := claimsOf(ctx)
+	inRoom := claims.Kind == auth.RoomToken && exec.RoomID != "" && claims.Room == exec.RoomID && !kicked(claims)
+	if errors.Is(err, store.ErrNotFound) || err == nil && claims.Subject != exec.Owner && !inRoom {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load execution: " + err.Error()})
+		return
+	}
+	if exec.Hidden {
+		exec = exec.Redacted()
 	}
+	ctx.JSON(http.StatusOK, exec)
 }
@@ -0,0 +1,39 @@
+// Command worker runs executions for an API server started with
+// WORKER_GRPC_ADDR, so execution scales separately from collaboration.
+package main
+
+import (
+	"log"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/executor/remote"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker"
+)
+
+func main() {
+	conf := configs.GetInstance()
+	if conf.WorkerGRPCAddr != "" {
+		log.Fatal("WORKER_GRPC_ADDR is for the API server; a worker runs executions itself")
+	}
+	if conf.WorkerToken == "" {
+		log.Fatal("WORKER_TOKEN must be set to the API server's worker token")
+	}
+	creds, err := remote.ClientCredentials(conf.APIGRPCTLS, conf.APIGRPCCA)
+	if err != nil {
+		log.Fatal("Failed to load the API server's CA certificate:", err)
+	}
+	languages := worker.WarmUp()
+	if len(languages) == 0 {
+		log.Fatal("No language is ready to run on this worker")
+	}
+	reg := remote.Registration{
+		ID:        conf.WorkerID,
+		Token:     conf.WorkerToken,
+		Languages: languages,
+		Capacity:  max(conf.WorkerCapacity, 1),
+	}
+	log.Printf("Worker %s connecting to %s", reg.ID, conf.APIGRPCAddr)
+	if err := remote.RunWorker(conf.APIGRPCAddr, creds, reg, worker.GetExecutor); err != nil {
+		log.Fatal("Failed to connect to the API server:", err)
+	}
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// OIDC login is the authorization code flow: the user is sent to the
+// provider with a state and a nonce, comes back to the callback with a code,
+// and the code is exchanged for an ID token saying who they are.
+
+// oidcProvider is the part of the provider's discovery document we use.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// idTokenClaims are the ID token claims we check or use.
+type idTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	Nonce             string   `json:"nonce"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+}
+
+// audience is the aud claim, which is a string or an array of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// OIDCIdentity is who the provider says logged in.
+type OIDCIdentity struct {
+	// Subject is the provider's ID for the user; Username is picked from
+	// their preferred username or e-mail address.
+	Subject  string
+	Username string
+}
+
+var (
+	provider   *oidcProvider
+	providerMu sync.Mutex
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// OIDCEnabled reports whether an OIDC provider is configured.
+func OIDCEnabled() bool {
+	conf := configs.GetInstance()
+	return conf.OIDCIssuer != "" && conf.OIDCClientID != ""
+}
+
+// discover fetches the provider's discovery document once it succeeds.
+func discover(ctx context.Context) (*oidcProvider, error) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	if provider != nil {
+		return provider, nil
+	}
+	issuer := strings.TrimSuffix(configs.GetInstance().OIDCIssuer, "/")
+	var p oidcProvider
+	if err := getJSON(ctx, issuer+"/.well-known/openid-configuration", &p); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != issuer || p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" {
+		return nil, errors.New("OIDC discovery failed: the provider's document doesn't match its issuer")
+	}
+	provider = &p
+	return provider, nil
+}
+
+func getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// OIDCLogin returns the provider page to send the user to, and the state
+// and nonce the callback must be checked against.
+func OIDCLogin(ctx context.Context) (authURL, state, nonce string, err error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return "", "", "", err
+	}
+	state, nonce = randomString(), randomString()
+	conf := configs.GetInstance()
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {conf.OIDCClientID},
+		"redirect_uri":  {conf.OIDCRedirectURL},
+		"scope":         {"openid profile email"},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.AuthorizationEndpoint + sep + query.Encode(), state, nonce, nil
+}
+
+// OIDCExchange redeems code at the provider's token endpoint and returns
+// the identity in the ID token, which must carry nonce.
+//
+// The ID token comes straight from the token endpoint over TLS, so, as the
+// OIDC spec allows for this flow, its claims are checked but its signature
+// isn't.
+func OIDCExchange(ctx context.Context, code, nonce string) (OIDCIdentity, error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+	conf := configs.GetInstance()
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {conf.OIDCRedirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(conf.OIDCClientID), url.QueryEscape(conf.OIDCClientSecret))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return OIDCIdentity{}, fmt.Errorf("OIDC token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return OIDCIdentity{}, fmt.Errorf("OIDC token exchange failed: %s", resp.Status)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return OIDCIdentity{}, fmt.Errorf("OIDC token exchange failed: %w", err)
+	}
+
+	parts := strings.Split(tokens.IDToken, ".")
+	if len(parts) != 3 {
+		return OIDCIdentity{}, errors.New("the provider returned no ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return OIDCIdentity{}, fmt.Errorf("malformed ID token: %w", err)
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return OIDCIdentity{}, fmt.Errorf("malformed ID token: %w", err)
+	}
+	switch {
+	case claims.Issuer != p.Issuer:
+		return OIDCIdentity{}, errors.New("the ID token is from another issuer")
+	case !slices.Contains(claims.Audience, conf.OIDCClientID):
+		return OIDCIdentity{}, errors.New("the ID token is for another client")
+	case time.Now().Unix() >= claims.ExpiresAt:
+		return OIDCIdentity{}, errors.New("the ID token has expired")
+	case claims.Nonce != nonce:
+		return OIDCIdentity{}, errors.New("the ID token is for another login")
+	case claims.Subject == "":
+		return OIDCIdentity{}, errors.New("the ID token has no subject")
+	}
+
+	identity := OIDCIdentity{Subject: claims.Subject}
+	for _, name := range []string{claims.PreferredUsername, claims.Email, claims.Subject} {
+		if model.ValidateUsername(name) == nil {
+			identity.Username = name
+			return identity, nil
+		}
+	}
+	return OIDCIdentity{}, errors.New("the provider gave no usable username")
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// Tokens are JWTs signed with HS256 under configs.AuthSecret. A login gives
+// a session token; a room is entered with a room token, got by creating the
+// room or by redeeming an invite token for it.
+
+// Kind tells the tokens apart, so one can't be used as another.
+type Kind string
+
+const (
+	SessionToken Kind = "session" // Subject is logged in
+	InviteToken  Kind = "invite"  // whoever redeems it may enter Room as Role
+	RoomToken    Kind = "room"    // Subject may enter Room as Role
+)
+
+// Claims is the payload of a token.
+type Claims struct {
+	Kind      Kind       `json:"kind"`
+	Subject   string     `json:"sub,omitempty"`
+	Room      string     `json:"room,omitempty"`
+	Role      model.Role `json:"role,omitempty"`
+	IssuedAt  int64      `json:"iat"`
+	ExpiresAt int64      `json:"exp"` // in Unix seconds
+}
+
+// ErrInvalidToken is returned for tokens that are malformed, badly signed,
+// expired or of the wrong kind.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// header is the JOSE header of every token.
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+var (
+	key     []byte
+	keyOnce sync.Once
+)
+
+func signingKey() []byte {
+	keyOnce.Do(func() {
+		if secret := configs.GetInstance().AuthSecret; secret != "" {
+			key = []byte(secret)
+			return
+		}
+		key = make([]byte, 32)
+		rand.Read(key)
+		log.Println("Warning: AUTH_SECRET is not set; tokens stop working when the server restarts")
+	})
+	return key
+}
+
+// sessionTTL is how long session and room tokens last.
+func sessionTTL() time.Duration {
+	return time.Duration(configs.GetInstance().SessionTTL) * time.Minute
+}
+
+// Sign encodes and signs claims, stamping IssuedAt.
+func Sign(claims Claims) (string, error) {
+	claims.IssuedAt = time.Now().Unix()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + signature(signed), nil
+}
+
+func signature(signed string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks token's signature and expiry and that it is one of kinds,
+// and returns its claims.
+func Verify(token string, kinds ...Kind) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return Claims{}, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signature(parts[0]+"."+parts[1]))) {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt || !slices.Contains(kinds, claims.Kind) {
+		return Claims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// FromRequest verifies the token of r: the Authorization bearer token or,
+// for WebSockets, which browsers can't give headers, the token query param.
+func FromRequest(r *http.Request, kinds ...Kind) (Claims, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return Claims{}, errors.New("missing token")
+	}
+	return Verify(token, kinds...)
+}
+
+// NewSessionToken returns a session token for username.
+func NewSessionToken(username string) (string, error) {
+	return Sign(Claims{Kind: SessionToken, Subject: username, ExpiresAt: time.Now().Add(sessionTTL()).Unix()})
+}
+
+// NewRoomToken returns a token letting username into roomID as role.
+func NewRoomToken(username, roomID string, role model.Role) (string, error) {
+	return Sign(Claims{Kind: RoomToken, Subject: username, Room: roomID, Role: role, ExpiresAt: time.Now().Add(sessionTTL()).Unix()})
+}
+
+// NewInviteToken returns an invite into roomID as role, redeemable until
+// expiresAt.
+func NewInviteToken(roomID string, role model.Role, expiresAt time.Time) (string, error) {
+	return Sign(Claims{Kind: InviteToken, Room: roomID, Role: role, ExpiresAt: expiresAt.Unix()})
+}
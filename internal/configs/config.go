@@ -0,0 +1,161 @@
+package configs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Config holds runtime settings read from the environment.
+// Every field has a default so `go run main.go` works without any setup.
+type Config struct {
+	// DBPath is the embedded database file holding rooms and their history.
+	DBPath string
+	// SnapshotInterval is how many accepted deltas pass between room snapshots.
+	SnapshotInterval int
+	// WorkerConcurrency is how many executions run at the same time.
+	WorkerConcurrency int
+	// JobQueueSize is how many submitted executions may wait for a worker
+	// before /submit starts rejecting new ones.
+	JobQueueSize int
+	// PoolSize is how many idle, pre-started containers each language keeps
+	// ready. 0 starts a fresh container for every execution.
+	PoolSize int
+	// PoolMaxReuse is how many executions a pooled container serves before it
+	// is replaced.
+	PoolMaxReuse int
+	// TerminalImage is the image room terminals run in.
+	TerminalImage string
+	// TerminalMemoryLimit is the memory limit of a room terminal, in MB.
+	TerminalMemoryLimit int
+	// InteractiveTimeLimit is how long an interactive run may last, in ms.
+	InteractiveTimeLimit int
+	// LanguagesFile replaces the built-in language registry when set.
+	LanguagesFile string
+	// ExecutionBackend runs the code: "docker", "local" (bubblewrap on the
+	// host, for machines without Docker) or "fake" (nothing runs; for tests).
+	ExecutionBackend string
+	// LocalCgroupRoot is a delegated cgroup v2 directory under which the
+	// local backend limits each run. Empty runs without cgroup limits.
+	LocalCgroupRoot string
+	// WorkerGRPCAddr is where the API server listens for remote workers. When
+	// set, executions are dispatched to them instead of running in-process.
+	WorkerGRPCAddr string
+	// APIGRPCAddr is the API server a standalone worker registers with.
+	APIGRPCAddr string
+	// WorkerToken must be presented by remote workers. The API server refuses
+	// to accept workers without one.
+	WorkerToken string
+	// WorkerTLSCert and WorkerTLSKey, when set, serve WorkerGRPCAddr over TLS.
+	WorkerTLSCert string
+	WorkerTLSKey  string
+	// APIGRPCTLS makes a standalone worker connect over TLS, verifying the API
+	// server against APIGRPCCA or, when that is empty, the system's roots.
+	APIGRPCTLS bool
+	APIGRPCCA  string
+	// WorkerID names a standalone worker; defaults to the host name.
+	WorkerID string
+	// WorkerCapacity is how many jobs a standalone worker runs at a time.
+	WorkerCapacity int
+	// AuthSecret signs login and room tokens. Empty uses a random key, so
+	// every token is invalidated when the server restarts.
+	AuthSecret string
+	// SessionTTL is how long a login or room token lasts, in minutes.
+	SessionTTL int
+	// AdminUsers are the users who may edit the problem bank, hidden tests
+	// included.
+	AdminUsers []string
+	// OIDCIssuer enables login through an OpenID Connect provider, e.g.
+	// https://accounts.google.com. OIDCRedirectURL is this server's
+	// /auth/oidc/callback as registered with the provider.
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	// OIDCReturnURL is the web client page an OIDC login ends on, with the
+	// login token in its URL fragment. Empty answers the callback with JSON.
+	OIDCReturnURL string
+}
+
+var instance *Config
+var once sync.Once
+
+func GetInstance() *Config {
+	once.Do(func() {
+		instance = &Config{
+			DBPath:               getEnv("DB_PATH", "data/go-ide-pair.db"),
+			SnapshotInterval:     getEnvInt("SNAPSHOT_INTERVAL", 50),
+			WorkerConcurrency:    getEnvInt("WORKER_CONCURRENCY", 2),
+			JobQueueSize:         getEnvInt("JOB_QUEUE_SIZE", 100),
+			PoolSize:             getEnvInt("POOL_SIZE", 1),
+			PoolMaxReuse:         getEnvInt("POOL_MAX_REUSE", 20),
+			TerminalImage:        getEnv("TERMINAL_IMAGE", "buildpack-deps:bookworm"),
+			TerminalMemoryLimit:  getEnvInt("TERMINAL_MEMORY_LIMIT", 1024),
+			InteractiveTimeLimit: getEnvInt("INTERACTIVE_TIME_LIMIT", 300000),
+			LanguagesFile:        getEnv("LANGUAGES_FILE", ""),
+			ExecutionBackend:     getEnv("EXECUTION_BACKEND", "docker"),
+			LocalCgroupRoot:      getEnv("LOCAL_CGROUP_ROOT", ""),
+			WorkerGRPCAddr:       getEnv("WORKER_GRPC_ADDR", ""),
+			APIGRPCAddr:          getEnv("API_GRPC_ADDR", "localhost:9090"),
+			WorkerToken:          getEnv("WORKER_TOKEN", ""),
+			WorkerTLSCert:        getEnv("WORKER_TLS_CERT", ""),
+			WorkerTLSKey:         getEnv("WORKER_TLS_KEY", ""),
+			APIGRPCTLS:           getEnvBool("API_GRPC_TLS", false),
+			APIGRPCCA:            getEnv("API_GRPC_CA", ""),
+			WorkerID:             getEnv("WORKER_ID", hostname()),
+			WorkerCapacity:       getEnvInt("WORKER_CAPACITY", 2),
+			AuthSecret:           getEnv("AUTH_SECRET", ""),
+			SessionTTL:           getEnvInt("SESSION_TTL", 720),
+			AdminUsers:           getEnvList("ADMIN_USERS"),
+			OIDCIssuer:           getEnv("OIDC_ISSUER", ""),
+			OIDCClientID:         getEnv("OIDC_CLIENT_ID", ""),
+			OIDCClientSecret:     getEnv("OIDC_CLIENT_SECRET", ""),
+			OIDCRedirectURL:      getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback"),
+			OIDCReturnURL:        getEnv("OIDC_RETURN_URL", ""),
+		}
+	})
+	return instance
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "worker"
+	}
+	return name
+}
+
+func getEnvInt(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
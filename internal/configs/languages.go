@@ -0,0 +1,227 @@
+package configs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed languages.yaml
+var defaultLanguages []byte
+
+// InputMode is how a test case line reaches the program.
+type InputMode string
+
+const (
+	StdinInput        InputMode = "stdin"        // values, one per line, on stdin
+	AssignmentsInput  InputMode = "assignments"  // Python assignments prepended to the code
+	DeclarationsInput InputMode = "declarations" // JavaScript declarations prepended to the code
+)
+
+// Language is one entry of the language registry; see languages.yaml.
+type Language struct {
+	ID                   model.ProgrammingLanguage `yaml:"id" json:"id"`
+	Name                 string                    `yaml:"name" json:"name"`
+	AceMode              string                    `yaml:"aceMode" json:"aceMode"`
+	Image                string                    `yaml:"image" json:"image"`
+	SourceFile           string                    `yaml:"sourceFile" json:"sourceFile"`
+	Compile              string                    `yaml:"compile" json:"-"`
+	Run                  string                    `yaml:"run" json:"-"`
+	InteractiveRun       string                    `yaml:"interactiveRun" json:"-"`
+	Input                InputMode                 `yaml:"input" json:"input"`
+	TimeLimit            int                       `yaml:"timeLimit" json:"timeLimit"`
+	MemoryLimit          int                       `yaml:"memoryLimit" json:"memoryLimit"`
+	TotalTimeLimit       int                       `yaml:"totalTimeLimit" json:"totalTimeLimit"`
+	CompileErrorExitCode int                       `yaml:"compileErrorExitCode" json:"-"`
+	Standards            []string                  `yaml:"standards" json:"standards,omitempty"`
+	Optimizations        []string                  `yaml:"optimizations" json:"optimizations,omitempty"`
+	Sandbox              Sandbox                   `yaml:"sandbox" json:"-"`
+}
+
+// Sandbox hardens the containers a language's code runs in. The zero value
+// is the hardened default; a language relaxes only what its toolchain needs.
+type Sandbox struct {
+	// Network gives the container a network; off by default.
+	Network bool `yaml:"network"`
+	// WritableRootfs leaves the root filesystem writable. By default it is
+	// read-only and only the /workdir and /tmp tmpfs mounts can be written.
+	WritableRootfs bool `yaml:"writableRootfs"`
+	// WorkdirSize and TmpSize are the sizes of the /workdir and /tmp tmpfs
+	// mounts, in MB.
+	WorkdirSize int `yaml:"workdirSize"`
+	TmpSize     int `yaml:"tmpSize"`
+	// PidsLimit caps the processes and threads of a run, which stops fork bombs.
+	PidsLimit int64 `yaml:"pidsLimit"`
+	// User runs the code; nobody by default.
+	User string `yaml:"user"`
+	// CapAdd lists the capabilities given back after dropping all of them.
+	CapAdd []string `yaml:"capAdd"`
+	// AllowNewPrivileges lets setuid binaries gain privileges.
+	AllowNewPrivileges bool `yaml:"allowNewPrivileges"`
+	// SeccompProfile is a seccomp profile file, relative to the registry
+	// file, or "unconfined". Docker's default profile applies when empty.
+	SeccompProfile string `yaml:"seccompProfile"`
+	// Seccomp is the content of SeccompProfile, loaded with the registry.
+	Seccomp string `yaml:"-"`
+	// Ulimits are added to the default limits, by name (nofile, fsize, …).
+	Ulimits map[string]int64 `yaml:"ulimits"`
+	// HostPaths are added to defaultHostPaths: the host paths the local
+	// backend mounts, read-only, for the toolchain. Nothing else of the
+	// host is visible to a local run.
+	HostPaths []string `yaml:"hostPaths"`
+}
+
+// defaultUlimits apply unless a language's sandbox overrides them.
+var defaultUlimits = map[string]int64{
+	"nofile": 256,
+	"core":   0,
+}
+
+// defaultHostPaths hold the toolchains and shared libraries of most hosts.
+// Missing ones are skipped.
+var defaultHostPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32",
+	"/etc/alternatives", "/etc/ld.so.cache", "/etc/ld.so.conf", "/etc/ld.so.conf.d",
+}
+
+var languages []Language
+var languagesOnce sync.Once
+
+// GetLanguages returns the language registry in the order it lists them:
+// the file named by LANGUAGES_FILE, or the built-in languages.yaml.
+func GetLanguages() []Language {
+	languagesOnce.Do(func() {
+		data, source, baseDir := defaultLanguages, "built-in languages.yaml", "."
+		if path := GetInstance().LanguagesFile; path != "" {
+			var err error
+			if data, err = os.ReadFile(path); err != nil {
+				log.Fatalf("Failed to read language registry: %v", err)
+			}
+			source, baseDir = path, filepath.Dir(path)
+		}
+		var err error
+		if languages, err = ParseLanguages(data, baseDir); err != nil {
+			log.Fatalf("Invalid language registry %s: %v", source, err)
+		}
+	})
+	return languages
+}
+
+// GetLanguage returns the registry entry for id.
+func GetLanguage(id model.ProgrammingLanguage) (Language, bool) {
+	for _, lang := range GetLanguages() {
+		if lang.ID == id {
+			return lang, true
+		}
+	}
+	return Language{}, false
+}
+
+// ParseLanguages parses a language registry, filling in defaults. Seccomp
+// profiles are read relative to baseDir.
+func ParseLanguages(data []byte, baseDir string) ([]Language, error) {
+	var parsed []Language
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	seen := make(map[model.ProgrammingLanguage]bool)
+	for i := range parsed {
+		lang := &parsed[i]
+		if seen[lang.ID] {
+			return nil, fmt.Errorf("language %d is listed twice", lang.ID)
+		}
+		seen[lang.ID] = true
+
+		if lang.Input == "" {
+			lang.Input = StdinInput
+		}
+		if lang.TimeLimit == 0 {
+			lang.TimeLimit = 10000
+		}
+		if lang.MemoryLimit == 0 {
+			lang.MemoryLimit = 1024
+		}
+		if lang.TotalTimeLimit == 0 {
+			lang.TotalTimeLimit = 60000
+		}
+		if lang.CompileErrorExitCode == 0 {
+			lang.CompileErrorExitCode = 100
+		}
+		if lang.InteractiveRun == "" {
+			lang.InteractiveRun = lang.Run
+		}
+		if err := lang.Sandbox.applyDefaults(baseDir); err != nil {
+			return nil, fmt.Errorf("language %d: %v", lang.ID, err)
+		}
+
+		switch {
+		case lang.Name == "" || lang.Image == "" || lang.SourceFile == "" || lang.Run == "":
+			return nil, fmt.Errorf("language %d: name, image, sourceFile and run are required", lang.ID)
+		case lang.Input != StdinInput && lang.Input != AssignmentsInput && lang.Input != DeclarationsInput:
+			return nil, fmt.Errorf("language %d: unknown input mode %q", lang.ID, lang.Input)
+		case strings.Contains(lang.Compile, "{standard}") && len(lang.Standards) == 0:
+			return nil, fmt.Errorf("language %d: compile uses {standard} but no standards are listed", lang.ID)
+		case strings.Contains(lang.Compile, "{optimization}") && len(lang.Optimizations) == 0:
+			return nil, fmt.Errorf("language %d: compile uses {optimization} but no optimizations are listed", lang.ID)
+		}
+	}
+	return parsed, nil
+}
+
+// TerminalSandbox returns the sandbox of room terminals: the hardened
+// defaults, with room for a checkout and a build.
+func TerminalSandbox() Sandbox {
+	s := Sandbox{WorkdirSize: 256, TmpSize: 256, PidsLimit: 256}
+	// Cannot fail: there is no seccomp profile to read.
+	_ = s.applyDefaults("")
+	return s
+}
+
+func (s *Sandbox) applyDefaults(baseDir string) error {
+	if s.WorkdirSize == 0 {
+		s.WorkdirSize = 64
+	}
+	if s.TmpSize == 0 {
+		s.TmpSize = 64
+	}
+	if s.PidsLimit == 0 {
+		s.PidsLimit = 128
+	}
+	if s.User == "" {
+		s.User = "65534:65534"
+	}
+	ulimits := maps.Clone(defaultUlimits)
+	maps.Copy(ulimits, s.Ulimits)
+	s.Ulimits = ulimits
+	s.HostPaths = append(slices.Clone(defaultHostPaths), s.HostPaths...)
+
+	switch s.SeccompProfile {
+	case "":
+	case "unconfined":
+		s.Seccomp = "unconfined"
+	default:
+		path := s.SeccompProfile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read seccomp profile: %v", err)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("seccomp profile %s is not valid JSON", path)
+		}
+		s.Seccomp = string(data)
+	}
+	return nil
+}
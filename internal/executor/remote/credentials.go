@@ -0,0 +1,37 @@
+package remote
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServerCredentials returns the transport credentials the API server accepts
+// workers with: TLS with the certificate and key in certFile and keyFile, or
+// plaintext when both are empty.
+func ServerCredentials(certFile, keyFile string) (credentials.TransportCredentials, error) {
+	if certFile == "" && keyFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("a TLS certificate needs both its certificate and key file")
+	}
+	return credentials.NewServerTLSFromFile(certFile, keyFile)
+}
+
+// ClientCredentials returns the transport credentials a worker connects
+// with: TLS when useTLS is set or caFile is given, verifying the API server
+// against the CA certificate in caFile or the system's roots, and plaintext
+// otherwise.
+func ClientCredentials(useTLS bool, caFile string) (credentials.TransportCredentials, error) {
+	switch {
+	case caFile != "":
+		return credentials.NewClientTLSFromFile(caFile, "")
+	case useTLS:
+		return credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12}), nil
+	default:
+		return insecure.NewCredentials(), nil
+	}
+}
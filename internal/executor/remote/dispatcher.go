@@ -0,0 +1,348 @@
+package remote
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// maxAttempts is how many workers a job is tried on before it fails: a job
+// whose worker disconnects before answering is sent to another one.
+const maxAttempts = 3
+
+// dispatchTimeout is how long a job waits for a worker of its language to
+// have a free slot — long enough for a crashed worker to come back.
+const dispatchTimeout = 2 * time.Minute
+
+// errWorkerLost is returned for a job whose worker disconnected.
+var errWorkerLost = errors.New("worker disconnected")
+
+// Dispatcher accepts remote workers and runs executions on them. It is a
+// job_executor.JobExecutor for every language.
+type Dispatcher struct {
+	token string
+
+	mu      sync.Mutex
+	workers map[*remoteWorker]bool
+	// changed is closed and replaced whenever a worker registers or frees a
+	// slot.
+	changed chan struct{}
+}
+
+// remoteWorker is a connected worker.
+type remoteWorker struct {
+	Registration
+	languages map[model.ProgrammingLanguage]bool
+	running   int // guarded by Dispatcher.mu
+
+	sendMu sync.Mutex
+	stream grpc.ServerStream
+
+	jobsMu sync.Mutex
+	jobs   map[string]*remoteJob
+	done   chan struct{} // closed once the worker disconnects
+}
+
+type remoteJob struct {
+	onOutput job_executor.OutputHandler
+	result   chan job_executor.JobExecutorOutput
+}
+
+var instance *Dispatcher
+var once sync.Once
+
+func GetInstance() *Dispatcher {
+	once.Do(func() {
+		instance = NewDispatcher(configs.GetInstance().WorkerToken)
+	})
+	return instance
+}
+
+// NewDispatcher returns a dispatcher accepting workers that present token.
+// Serve refuses to start without one.
+func NewDispatcher(token string) *Dispatcher {
+	return &Dispatcher{
+		token:   token,
+		workers: make(map[*remoteWorker]bool),
+		changed: make(chan struct{}),
+	}
+}
+
+// Serve accepts workers on addr, over creds, until the listener fails.
+func (d *Dispatcher) Serve(addr string, creds credentials.TransportCredentials) error {
+	if d.token == "" {
+		return errors.New("remote workers need a shared token (WORKER_TOKEN)")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(
+		grpc.Creds(creds),
+		// Find workers that vanished without closing their connection.
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: 10 * time.Second, Timeout: 5 * time.Second}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: 5 * time.Second, PermitWithoutStream: true}),
+	)
+	server.RegisterService(&serviceDesc, d)
+	log.Printf("Accepting remote workers on %s", addr)
+	return server.Serve(lis)
+}
+
+// Validate rejects unsupported standards and optimization levels up front,
+// like a local executor.
+func (d *Dispatcher) Validate(source model.SourceCode) error {
+	lang, ok := configs.GetLanguage(source.Language)
+	if !ok {
+		return fmt.Errorf("unsupported language: %d", source.Language)
+	}
+	_, err := job_executor.CompileCommand(lang, source)
+	return err
+}
+
+// Execute runs source on the least-loaded worker of its language, and on
+// another one if that worker is lost before answering.
+func (d *Dispatcher) Execute(source model.SourceCode, onOutput job_executor.OutputHandler) job_executor.JobExecutorOutput {
+	return d.execute(source, nil, maxAttempts, onOutput)
+}
+
+// ExecuteInteractive runs source interactively on the least-loaded worker of
+// its language, forwarding stdin to it. It is not retried: the input already
+// sent cannot be replayed.
+func (d *Dispatcher) ExecuteInteractive(source model.SourceCode, stdin io.Reader, onOutput job_executor.OutputHandler) job_executor.JobExecutorOutput {
+	source.Interactive = true
+	return d.execute(source, stdin, 1, onOutput)
+}
+
+func (d *Dispatcher) execute(source model.SourceCode, stdin io.Reader, attempts int, onOutput job_executor.OutputHandler) job_executor.JobExecutorOutput {
+	var forward job_executor.OutputHandler
+	retried := &retriedOutput{onOutput: onOutput, sent: make(map[string]int)}
+	if onOutput != nil {
+		forward = retried.forward
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var w *remoteWorker
+		if w, err = d.acquire(source.Language); err != nil {
+			break
+		}
+		retried.attempt()
+		var output job_executor.JobExecutorOutput
+		output, err = w.run(source, stdin, forward)
+		d.release(w)
+		if err == nil {
+			return output
+		}
+		log.Printf("Worker %s: job failed (attempt %d of %d): %v", w.ID, attempt, attempts, err)
+	}
+	return job_executor.JobExecutorOutput{Status: job_executor.RuntimeError, Stderr: fmt.Sprintf("Failed to run on a remote worker: %v", err)}
+}
+
+// retriedOutput forwards a job's output over all of its attempts. A retry
+// prints again what the lost attempt already forwarded, so each stream
+// skips as many bytes as were forwarded before it.
+type retriedOutput struct {
+	onOutput job_executor.OutputHandler
+
+	mu   sync.Mutex
+	sent map[string]int // bytes forwarded per stream, over all attempts
+	seen map[string]int // bytes of the current attempt per stream
+}
+
+// attempt starts counting a new attempt's output.
+func (o *retriedOutput) attempt() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seen = make(map[string]int)
+}
+
+func (o *retriedOutput) forward(chunk job_executor.OutputChunk) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	seen := o.seen[chunk.Stream]
+	o.seen[chunk.Stream] += len(chunk.Data)
+	if skip := o.sent[chunk.Stream] - seen; skip > 0 {
+		if skip >= len(chunk.Data) {
+			return
+		}
+		chunk.Data = chunk.Data[skip:]
+	}
+	o.sent[chunk.Stream] += len(chunk.Data)
+	o.onOutput(chunk)
+}
+
+// acquire takes a slot on the least-loaded worker of lang, waiting up to
+// dispatchTimeout for one to be free.
+func (d *Dispatcher) acquire(lang model.ProgrammingLanguage) (*remoteWorker, error) {
+	deadline := time.After(dispatchTimeout)
+	for {
+		d.mu.Lock()
+		var best *remoteWorker
+		for w := range d.workers {
+			if !w.languages[lang] || w.running >= w.Capacity {
+				continue
+			}
+			// Compare running/capacity without dividing.
+			if best == nil || w.running*best.Capacity < best.running*w.Capacity {
+				best = w
+			}
+		}
+		if best != nil {
+			best.running++
+			d.mu.Unlock()
+			return best, nil
+		}
+		changed := d.changed
+		d.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return nil, fmt.Errorf("no worker for language %d became available within %v", lang, dispatchTimeout)
+		}
+	}
+}
+
+func (d *Dispatcher) release(w *remoteWorker) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w.running--
+	d.notify()
+}
+
+// notify wakes the jobs waiting in acquire. d.mu must be held.
+func (d *Dispatcher) notify() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// connect serves one worker's stream: it registers the worker, then routes
+// the output and results it sends to the waiting jobs until it disconnects.
+func (d *Dispatcher) connect(stream grpc.ServerStream) error {
+	var msg WorkerMessage
+	if err := stream.RecvMsg(&msg); err != nil {
+		return err
+	}
+	if msg.Register == nil {
+		return status.Error(codes.InvalidArgument, "the first message must be a registration")
+	}
+	if d.token == "" || subtle.ConstantTimeCompare([]byte(msg.Register.Token), []byte(d.token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid worker token")
+	}
+	w := &remoteWorker{
+		Registration: *msg.Register,
+		languages:    make(map[model.ProgrammingLanguage]bool),
+		stream:       stream,
+		jobs:         make(map[string]*remoteJob),
+		done:         make(chan struct{}),
+	}
+	w.Token = ""
+	for _, lang := range w.Languages {
+		w.languages[lang] = true
+	}
+	d.mu.Lock()
+	d.workers[w] = true
+	d.notify()
+	d.mu.Unlock()
+	log.Printf("Worker %s registered: languages %v, capacity %d", w.ID, w.Languages, w.Capacity)
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.workers, w)
+		d.mu.Unlock()
+		close(w.done)
+		log.Printf("Worker %s disconnected", w.ID)
+	}()
+	for {
+		var msg WorkerMessage
+		if err := stream.RecvMsg(&msg); err != nil {
+			return err
+		}
+		switch {
+		case msg.Output != nil:
+			if job := w.job(msg.Output.JobID, false); job != nil && job.onOutput != nil {
+				job.onOutput(msg.Output.Chunk)
+			}
+		case msg.Result != nil:
+			if job := w.job(msg.Result.JobID, true); job != nil {
+				job.result <- msg.Result.Output
+			}
+		}
+	}
+}
+
+// job looks up a running job, removing it when remove is set.
+func (w *remoteWorker) job(id string, remove bool) *remoteJob {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+	job := w.jobs[id]
+	if remove {
+		delete(w.jobs, id)
+	}
+	return job
+}
+
+// send sends msg to the worker.
+func (w *remoteWorker) send(msg *ServerMessage) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	return w.stream.SendMsg(msg)
+}
+
+// run sends source to the worker, forwards stdin when set, and waits for its
+// result.
+func (w *remoteWorker) run(source model.SourceCode, stdin io.Reader, onOutput job_executor.OutputHandler) (job_executor.JobExecutorOutput, error) {
+	id := uuid.NewString()
+	job := &remoteJob{onOutput: onOutput, result: make(chan job_executor.JobExecutorOutput, 1)}
+	w.jobsMu.Lock()
+	w.jobs[id] = job
+	w.jobsMu.Unlock()
+	defer w.job(id, true)
+
+	if err := w.send(&ServerMessage{Job: &Job{ID: id, Source: source}}); err != nil {
+		return job_executor.JobExecutorOutput{}, err
+	}
+	if stdin != nil {
+		// Ends when the caller closes stdin after the run; input for a
+		// finished job is ignored by the worker.
+		go func() {
+			buf := make([]byte, 4096)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 && w.send(&ServerMessage{Input: &JobInput{JobID: id, Data: string(buf[:n])}}) != nil {
+					return
+				}
+				if err != nil {
+					_ = w.send(&ServerMessage{Input: &JobInput{JobID: id, EOF: true}})
+					return
+				}
+			}
+		}()
+	}
+	select {
+	case output := <-job.result:
+		return output, nil
+	case <-w.done:
+		// The result may have come in just before the disconnect.
+		select {
+		case output := <-job.result:
+			return output, nil
+		default:
+			return job_executor.JobExecutorOutput{}, errWorkerLost
+		}
+	}
+}
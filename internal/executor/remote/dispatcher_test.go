@@ -0,0 +1,231 @@
+package remote
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "test-token"
+
+// startDispatcher serves a new dispatcher on an in-memory listener and
+// returns it with a client connection to it.
+func startDispatcher(t *testing.T) (*Dispatcher, *grpc.ClientConn) {
+	t.Helper()
+	d := NewDispatcher(testToken)
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	server.RegisterService(&serviceDesc, d)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return d, conn
+}
+
+// testWorker is a worker the test drives by hand over its own stream.
+type testWorker struct {
+	stream grpc.ClientStream
+	// drop ends the stream, as a worker that crashed would.
+	drop context.CancelFunc
+}
+
+// connectWorker registers a worker with id, taking Python jobs, and waits
+// until d has accepted it.
+func connectWorker(t *testing.T, d *Dispatcher, conn *grpc.ClientConn, id string, capacity int) *testWorker {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], connectMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := Registration{ID: id, Token: testToken, Languages: []model.ProgrammingLanguage{model.Python3}, Capacity: capacity}
+	if err := stream.SendMsg(&WorkerMessage{Register: &reg}); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		d.mu.Lock()
+		registered, changed := false, d.changed
+		for w := range d.workers {
+			registered = registered || w.ID == id
+		}
+		d.mu.Unlock()
+		if registered {
+			return &testWorker{stream: stream, drop: cancel}
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatalf("worker %s was not registered", id)
+		}
+	}
+}
+
+// job waits for the next job sent to w.
+func (w *testWorker) job(t *testing.T) Job {
+	t.Helper()
+	var msg ServerMessage
+	if err := w.stream.RecvMsg(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Job == nil {
+		t.Fatalf("got %+v, want a job", msg)
+	}
+	return *msg.Job
+}
+
+func (w *testWorker) send(t *testing.T, msg *WorkerMessage) {
+	t.Helper()
+	if err := w.stream.SendMsg(msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (w *testWorker) output(t *testing.T, jobID, data string) {
+	t.Helper()
+	w.send(t, &WorkerMessage{Output: &JobOutput{JobID: jobID, Chunk: job_executor.OutputChunk{Stream: "stdout", Data: data}}})
+}
+
+// TestRetrySkipsForwardedOutput loses the first worker after it streamed
+// part of the output, and checks that the retry on another worker does not
+// print that part again.
+func TestRetrySkipsForwardedOutput(t *testing.T) {
+	d, conn := startDispatcher(t)
+	first := connectWorker(t, d, conn, "first", 1)
+
+	chunks := make(chan string, 10)
+	done := make(chan job_executor.JobExecutorOutput, 1)
+	go func() {
+		done <- d.Execute(model.SourceCode{Language: model.Python3, Content: "print('hello world')"}, func(chunk job_executor.OutputChunk) {
+			chunks <- chunk.Data
+		})
+	}()
+
+	job := first.job(t)
+	first.output(t, job.ID, "hello ")
+	select {
+	case got := <-chunks:
+		if got != "hello " {
+			t.Fatalf("forwarded %q, want %q", got, "hello ")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the output was not forwarded")
+	}
+	first.drop()
+
+	// The retry prints everything again, split differently.
+	second := connectWorker(t, d, conn, "second", 1)
+	job = second.job(t)
+	second.output(t, job.ID, "hel")
+	second.output(t, job.ID, "lo world\n")
+	second.send(t, &WorkerMessage{Result: &JobResult{JobID: job.ID, Output: job_executor.JobExecutorOutput{Status: job_executor.Successful, Stdout: "hello world\n"}}})
+
+	var output job_executor.JobExecutorOutput
+	select {
+	case output = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job did not finish")
+	}
+	if output.Status != job_executor.Successful || output.Stdout != "hello world\n" {
+		t.Errorf("got status %d, stdout %q", output.Status, output.Stdout)
+	}
+	forwarded := "hello "
+	for len(chunks) > 0 {
+		forwarded += <-chunks
+	}
+	if forwarded != "hello world\n" {
+		t.Errorf("forwarded %q over both attempts, want %q", forwarded, "hello world\n")
+	}
+}
+
+// TestAcquireWeighsCapacity checks that jobs go to the worker with the
+// smallest share of its capacity in use, not the fewest jobs, and that a job
+// waits for a free slot once every worker is full.
+func TestAcquireWeighsCapacity(t *testing.T) {
+	d, conn := startDispatcher(t)
+	connectWorker(t, d, conn, "small", 2)
+	connectWorker(t, d, conn, "large", 6)
+
+	var acquired []*remoteWorker
+	jobs := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		w, err := d.acquire(model.Python3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		acquired = append(acquired, w)
+		jobs[w.ID]++
+	}
+	if jobs["small"] != 1 || jobs["large"] != 3 {
+		t.Errorf("got %v, want 1 job on small and 3 on large", jobs)
+	}
+
+	for i := 0; i < 4; i++ {
+		w, err := d.acquire(model.Python3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		acquired = append(acquired, w)
+	}
+	waiting := make(chan *remoteWorker, 1)
+	go func() {
+		w, _ := d.acquire(model.Python3)
+		waiting <- w
+	}()
+	select {
+	case w := <-waiting:
+		t.Fatalf("got a slot on %s while every worker was full", w.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	d.release(acquired[0])
+	select {
+	case w := <-waiting:
+		if w != acquired[0] {
+			t.Errorf("got a slot on %s, want the one released on %s", w.ID, acquired[0].ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a released slot was not handed to the waiting job")
+	}
+}
+
+// TestRejectsWrongToken checks that a worker with the wrong token is turned
+// away.
+func TestRejectsWrongToken(t *testing.T) {
+	d, conn := startDispatcher(t)
+	stream, err := conn.NewStream(context.Background(), &serviceDesc.Streams[0], connectMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := Registration{ID: "intruder", Token: strings.Repeat("x", len(testToken)), Capacity: 1}
+	if err := stream.SendMsg(&WorkerMessage{Register: &reg}); err != nil {
+		t.Fatal(err)
+	}
+	var msg ServerMessage
+	if err := stream.RecvMsg(&msg); err == nil {
+		t.Fatal("a worker with the wrong token was accepted")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.workers) != 0 {
+		t.Errorf("%d workers registered", len(d.workers))
+	}
+}
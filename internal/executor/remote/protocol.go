@@ -0,0 +1,91 @@
+package remote
+
+import (
+	"encoding/json"
+
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// Workers and the API server talk over a single bidirectional gRPC stream,
+// opened by the worker. Messages are JSON rather than protobuf so they can
+// carry the model and executor types as they are.
+
+// Registration is the first message a worker sends.
+type Registration struct {
+	ID        string                      `json:"id"`
+	Token     string                      `json:"token,omitempty"`
+	Languages []model.ProgrammingLanguage `json:"languages"`
+	Capacity  int                         `json:"capacity"`
+}
+
+// Job is an execution the API server hands to a worker.
+type Job struct {
+	ID     string           `json:"id"`
+	Source model.SourceCode `json:"source"`
+}
+
+// JobInput is input for an interactive job. EOF ends its stdin.
+type JobInput struct {
+	JobID string `json:"jobId"`
+	Data  string `json:"data,omitempty"`
+	EOF   bool   `json:"eof,omitempty"`
+}
+
+// JobOutput is a piece of a job's output, streamed while it runs.
+type JobOutput struct {
+	JobID string                   `json:"jobId"`
+	Chunk job_executor.OutputChunk `json:"chunk"`
+}
+
+// JobResult is the final output of a job.
+type JobResult struct {
+	JobID  string                         `json:"jobId"`
+	Output job_executor.JobExecutorOutput `json:"output"`
+}
+
+// WorkerMessage is sent by workers; exactly one field is set.
+type WorkerMessage struct {
+	Register *Registration `json:"register,omitempty"`
+	Output   *JobOutput    `json:"output,omitempty"`
+	Result   *JobResult    `json:"result,omitempty"`
+}
+
+// ServerMessage is sent by the API server.
+type ServerMessage struct {
+	Job   *Job      `json:"job,omitempty"`
+	Input *JobInput `json:"input,omitempty"`
+}
+
+// jsonCodec is registered as the "json" content subtype, which both sides
+// select per call.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// dispatcherServer is implemented by Dispatcher; gRPC checks registered
+// services against it.
+type dispatcherServer interface {
+	connect(stream grpc.ServerStream) error
+}
+
+const connectMethod = "/goidepair.executor.Dispatcher/Connect"
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "goidepair.executor.Dispatcher",
+	HandlerType: (*dispatcherServer)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Connect",
+		Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(dispatcherServer).connect(stream) },
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
@@ -0,0 +1,124 @@
+package remote
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// reconnectDelay is how long a worker waits before reconnecting.
+const reconnectDelay = 2 * time.Second
+
+// RunWorker registers with the API server at addr, connecting over creds, as
+// reg and runs the jobs it receives with the executor executorFor returns.
+// The API server never sends more than reg.Capacity jobs at a time. A lost
+// connection is re-established; RunWorker only returns if the connection
+// cannot be set up at all or the API server rejects the worker's token.
+func RunWorker(addr string, creds credentials.TransportCredentials, reg Registration, executorFor func(model.ProgrammingLanguage) (job_executor.JobExecutor, error)) error {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 10 * time.Second, Timeout: 5 * time.Second, PermitWithoutStream: true}),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for {
+		err := serveJobs(conn, reg, executorFor)
+		if status.Code(err) == codes.Unauthenticated {
+			return err
+		}
+		log.Printf("Connection to %s lost: %v; reconnecting in %v", addr, err, reconnectDelay)
+		time.Sleep(reconnectDelay)
+	}
+}
+
+// serveJobs registers over a new stream and runs jobs until the stream
+// breaks. Jobs still running then are abandoned — the API server sends them
+// to another worker.
+func serveJobs(conn *grpc.ClientConn, reg Registration, executorFor func(model.ProgrammingLanguage) (job_executor.JobExecutor, error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], connectMethod, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+	var sendMu sync.Mutex
+	send := func(msg *WorkerMessage) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		if err := stream.SendMsg(msg); err != nil {
+			log.Printf("Failed to send to the API server: %v", err)
+		}
+	}
+	if err := stream.SendMsg(&WorkerMessage{Register: &reg}); err != nil {
+		return err
+	}
+	log.Printf("Registered as %s", reg.ID)
+
+	// inputs are the stdin of the interactive jobs running.
+	inputs := make(map[string]*job_executor.InputBuffer)
+	var inputsMu sync.Mutex
+	for {
+		var msg ServerMessage
+		if err := stream.RecvMsg(&msg); err != nil {
+			return err
+		}
+		if msg.Input != nil {
+			inputsMu.Lock()
+			in := inputs[msg.Input.JobID]
+			inputsMu.Unlock()
+			if in != nil {
+				_, _ = in.Write([]byte(msg.Input.Data))
+				if msg.Input.EOF {
+					in.Close()
+				}
+			}
+		}
+		if msg.Job == nil {
+			continue
+		}
+		var stdin *job_executor.InputBuffer
+		if msg.Job.Source.Interactive {
+			stdin = job_executor.NewInputBuffer()
+			inputsMu.Lock()
+			inputs[msg.Job.ID] = stdin
+			inputsMu.Unlock()
+		}
+		go func(job Job) {
+			onOutput := func(chunk job_executor.OutputChunk) {
+				send(&WorkerMessage{Output: &JobOutput{JobID: job.ID, Chunk: chunk}})
+			}
+			var output job_executor.JobExecutorOutput
+			executor, err := executorFor(job.Source.Language)
+			interactive, ok := executor.(job_executor.InteractiveExecutor)
+			switch {
+			case err != nil:
+				output = job_executor.JobExecutorOutput{Status: job_executor.RuntimeError, Stderr: err.Error()}
+			case stdin == nil:
+				output = executor.Execute(job.Source, onOutput)
+			case ok:
+				output = interactive.ExecuteInteractive(job.Source, stdin, onOutput)
+			default:
+				output = job_executor.JobExecutorOutput{Status: job_executor.RuntimeError, Stderr: "this worker cannot run interactively"}
+			}
+			if stdin != nil {
+				inputsMu.Lock()
+				delete(inputs, job.ID)
+				inputsMu.Unlock()
+				stdin.Close()
+			}
+			send(&WorkerMessage{Result: &JobResult{JobID: job.ID, Output: output}})
+		}(*msg.Job)
+	}
+}
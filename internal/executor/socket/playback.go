@@ -0,0 +1,94 @@
+package socket
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/auth"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// maxPlaybackGap caps how long playback waits between two events, so a
+// candidate thinking for ten minutes doesn't stall the review.
+const maxPlaybackGap = 5 * time.Second
+
+// playbackSpeeds are the supported replay multipliers.
+var playbackSpeeds = map[int]bool{1: true, 2: true, 4: true}
+
+// HandlePlayback streams a room's recorded event log back over a WebSocket,
+// preserving the original timing divided by the requested speed.
+//
+// Query params: token (required; a room token, which names the room, and
+// refused once its holder was kicked), speed (1, 2 or 4; default 1). The
+// stream ends with a "playback_end" message.
+func HandlePlayback(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.FromRequest(r, auth.RoomToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != model.Interviewer && IsKicked(claims.Room, claims.Subject) {
+		log.Printf("Rejected playback: %s was kicked from room %s", claims.Subject, claims.Room)
+		http.Error(w, "you were removed from this room", http.StatusForbidden)
+		return
+	}
+	roomID := claims.Room
+	speed := 1
+	if s := r.URL.Query().Get("speed"); s != "" {
+		if speed, err = strconv.Atoi(s); err != nil || !playbackSpeeds[speed] {
+			http.Error(w, "speed must be 1, 2 or 4", http.StatusBadRequest)
+			return
+		}
+	}
+	if roomStore == nil {
+		http.Error(w, "session recording is disabled", http.StatusServiceUnavailable)
+		return
+	}
+	events, err := roomStore.ListEvents(roomID)
+	if err != nil {
+		log.Printf("Playback of room %s: %v", roomID, err)
+		http.Error(w, "failed to load events", http.StatusInternalServerError)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("WebSocket upgrade error:", err)
+		return
+	}
+	defer ws.Close()
+
+	// The viewer never sends anything; reading only detects that it left.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	log.Printf("Playback of room %s started at %dx (%d events)", roomID, speed, len(events))
+	for i, event := range events {
+		if i > 0 {
+			gap := time.Duration(event.Timestamp-events[i-1].Timestamp) * time.Millisecond
+			gap = min(gap, maxPlaybackGap) / time.Duration(speed)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gap):
+			}
+		}
+		msg := Message{Type: event.Type, Payload: event.Payload, User: event.User, RoomID: roomID}
+		if err := ws.WriteJSON(msg); err != nil {
+			log.Printf("Playback of room %s aborted: %v", roomID, err)
+			return
+		}
+	}
+	ws.WriteJSON(Message{Type: "playback_end", RoomID: roomID})
+}
@@ -0,0 +1,89 @@
+package socket
+
+import (
+	"encoding/json"
+	"log"
+	"unicode/utf16"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/ot"
+)
+
+// problemSyncPayload is the payload of "problem_sync" messages.
+type problemSyncPayload struct {
+	ProblemID string                    `json:"problemId"`
+	Language  model.ProgrammingLanguage `json:"language"`
+	// StarterCode replaces the code of the room's entrypoint. It is not
+	// relayed; participants receive the replacement as a delta, or as a
+	// "file" message creating the entrypoint, instead.
+	StarterCode string `json:"starterCode,omitempty"`
+}
+
+// BindProblem binds roomID to a problem and replaces its code with starter,
+// creating the room if nobody has opened it yet.
+func BindProblem(roomID, problemID string, language model.ProgrammingLanguage, starter string) {
+	payload, _ := json.Marshal(problemSyncPayload{ProblemID: problemID, Language: language, StarterCode: starter})
+	broadcast <- Message{Type: "problem_sync", Payload: string(payload), RoomID: roomID}
+}
+
+// handleProblemSync applies a binding made by BindProblem and relays the new
+// code and problem to the room.
+func handleProblemSync(msg Message) {
+	var in problemSyncPayload
+	if err := json.Unmarshal([]byte(msg.Payload), &in); err != nil {
+		log.Printf("Room %s: malformed problem_sync: %v", msg.RoomID, err)
+		return
+	}
+	for _, change := range bindProblem(msg.RoomID, in) {
+		record(change)
+		sendTo(roomTargets(change), change)
+	}
+
+	in.StarterCode = ""
+	relayed, _ := json.Marshal(in)
+	msg.Payload = string(relayed)
+	record(msg)
+	sendTo(roomTargets(msg), msg)
+}
+
+// bindProblem records the binding on the room document and replaces the code
+// of its entrypoint with the starter code. It returns the "file" and "delta"
+// messages that make the change, stamped with their revisions.
+func bindProblem(roomID string, in problemSyncPayload) []Message {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	doc := getRoom(roomID)
+	doc.ProblemID = in.ProblemID
+	var changes []Message
+	rename, err := doc.changeLanguage(roomID, "", in.Language)
+	if err != nil {
+		log.Printf("Room %s: failed to rename the entrypoint: %v", roomID, err)
+	} else if rename != nil {
+		payload, _ := json.Marshal(rename)
+		changes = append(changes, Message{Type: "file", Payload: string(payload), RoomID: roomID})
+	}
+
+	path := doc.entrypoint()
+	content, exists := doc.Files[path]
+	if exists {
+		op := ot.New().Delete(len(utf16.Encode([]rune(content)))).Insert(in.StarterCode)
+		err = commit(roomID, "", doc, roomChange{path: path, op: op})
+		if err == nil {
+			payload, _ := json.Marshal(deltaPayload{Revision: doc.Revision, Path: path, Operation: op})
+			changes = append(changes, Message{Type: "delta", Payload: string(payload), RoomID: roomID})
+		}
+	} else {
+		change := model.FileChange{Action: model.CreateFile, Path: path, Content: in.StarterCode}
+		err = commit(roomID, "", doc, roomChange{path: path, file: &change})
+		if err == nil {
+			payload, _ := json.Marshal(filePayload{Revision: doc.Revision, FileChange: change})
+			changes = append(changes, Message{Type: "file", Payload: string(payload), RoomID: roomID})
+		}
+	}
+	if err != nil {
+		log.Printf("Room %s: failed to write the starter code: %v", roomID, err)
+	}
+	saveSnapshot(roomID, doc)
+	return changes
+}
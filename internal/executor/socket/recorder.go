@@ -0,0 +1,127 @@
+package socket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// recordedTypes are the message types that make up a room's timeline.
+// full_sync is only recorded once, when the room is first seeded, so playback
+// has a document to start from.
+var recordedTypes = map[string]bool{
+	"full_sync":       true,
+	"delta":           true,
+	"file":            true,
+	"entrypoint_sync": true,
+	"cursor":          true,
+	"input_sync":      true,
+	"output_sync":     true,
+	"language_sync":   true,
+	"run_started":     true,
+	"run_output":      true,
+	"run_input":       true,
+	"run_finished":    true,
+	"problem_sync":    true,
+	"lock_sync":       true,
+	"kick":            true,
+	"user_left":       true,
+}
+
+// documentTypes are the recorded types playback rebuilds the document from.
+// They are never dropped: without one of them every later edit would land
+// on the wrong text.
+var documentTypes = map[string]bool{
+	"full_sync":       true,
+	"delta":           true,
+	"file":            true,
+	"entrypoint_sync": true,
+	"language_sync":   true,
+	"problem_sync":    true,
+}
+
+// maxRecordBatch bounds how many events share a transaction.
+const maxRecordBatch = 1024
+
+// recordings decouples disk writes from HandleMessages so a slow fsync never
+// delays relaying edits to the other participants.
+var recordings = make(chan store.RecordedEvent, 1024)
+
+// record appends msg to its room's event log if it is part of the timeline.
+// Document changes wait for room in the queue; other events are dropped
+// once it is half full, so a flood of them never crowds out an edit.
+func record(msg Message) {
+	if roomStore == nil || !recordedTypes[msg.Type] {
+		return
+	}
+	e := store.RecordedEvent{
+		RoomID: msg.RoomID,
+		Event: model.RoomEvent{
+			Type:      msg.Type,
+			User:      msg.User,
+			Payload:   msg.Payload,
+			Timestamp: time.Now().UnixMilli(),
+		},
+	}
+	if documentTypes[msg.Type] {
+		recordings <- e
+		return
+	}
+	if len(recordings) < cap(recordings)/2 {
+		select {
+		case recordings <- e:
+			return
+		default:
+		}
+	}
+	log.Printf("Room %s: event log backlog full, dropping %s", msg.RoomID, msg.Type)
+}
+
+// recordEvents writes the queued events, everything queued meanwhile in one
+// transaction.
+func recordEvents(s store.RoomStore) {
+	for e := range recordings {
+		batch := []store.RecordedEvent{e}
+	drain:
+		for len(batch) < maxRecordBatch {
+			select {
+			case e := <-recordings:
+				if !coalesceOutput(&batch[len(batch)-1], e) {
+					batch = append(batch, e)
+				}
+			default:
+				break drain
+			}
+		}
+		if err := s.AppendEvents(batch); err != nil {
+			log.Printf("Failed to record %d room events: %v", len(batch), err)
+		}
+	}
+}
+
+// coalesceOutput appends next to last when both are output of the same run
+// and stream, so a chatty program costs one event per batch rather than one
+// per chunk. A run's output is capped by the executor, so the merged event
+// stays bounded.
+func coalesceOutput(last *store.RecordedEvent, next store.RecordedEvent) bool {
+	if last.RoomID != next.RoomID || last.Event.Type != "run_output" || next.Event.Type != "run_output" {
+		return false
+	}
+	var a, b runOutputPayload
+	if json.Unmarshal([]byte(last.Event.Payload), &a) != nil || json.Unmarshal([]byte(next.Event.Payload), &b) != nil {
+		return false
+	}
+	if a.ExecutionID != b.ExecutionID || a.Stream != b.Stream {
+		return false
+	}
+	a.Data += b.Data
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	last.Event.Payload = string(payload)
+	return true
+}
@@ -0,0 +1,35 @@
+package socket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+func outputEvent(roomID, executionID, stream, data string) store.RecordedEvent {
+	payload, _ := json.Marshal(runOutputPayload{ExecutionID: executionID, Stream: stream, Data: data})
+	return store.RecordedEvent{RoomID: roomID, Event: model.RoomEvent{Type: "run_output", Payload: string(payload)}}
+}
+
+func TestCoalesceOutput(t *testing.T) {
+	last := outputEvent("r", "e", "stdout", "hello ")
+	if !coalesceOutput(&last, outputEvent("r", "e", "stdout", "world")) {
+		t.Fatal("output of the same run and stream was not merged")
+	}
+	var merged runOutputPayload
+	if err := json.Unmarshal([]byte(last.Event.Payload), &merged); err != nil || merged.Data != "hello world" {
+		t.Errorf("merged payload is %s", last.Event.Payload)
+	}
+	for _, next := range []store.RecordedEvent{
+		outputEvent("other", "e", "stdout", "x"),
+		outputEvent("r", "other", "stdout", "x"),
+		outputEvent("r", "e", "stderr", "x"),
+		{RoomID: "r", Event: model.RoomEvent{Type: "delta", Payload: "{}"}},
+	} {
+		if coalesceOutput(&last, next) {
+			t.Errorf("%s %s was merged into the output", next.RoomID, next.Event.Payload)
+		}
+	}
+}
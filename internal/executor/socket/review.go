@@ -0,0 +1,109 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// Interviewers share a side channel the rest of the room never sees: the
+// room's review, markdown notes and a scorecard. Its messages only go to
+// interviewers and are left out of the recorded timeline, which anyone in
+// the room may replay.
+
+// reviewTypes are the messages of the side channel.
+var reviewTypes = map[string]bool{
+	"notes_sync":     true,
+	"scorecard_sync": true,
+}
+
+// loadReview returns the saved review of roomID, or an empty one.
+func loadReview(s store.RoomStore, roomID string) (model.RoomReview, error) {
+	review, err := s.GetReview(roomID)
+	if errors.Is(err, store.ErrNotFound) {
+		return model.RoomReview{Scorecard: model.NewScorecard()}, nil
+	}
+	return review, err
+}
+
+// handleReview applies a change to the notes or the scorecard and relays it
+// to the room's other interviewers. A scorecard that doesn't validate is
+// answered with the current one.
+func handleReview(msg Message) {
+	var card model.Scorecard
+	switch msg.Type {
+	case "notes_sync":
+		if len(msg.Payload) > model.MaxNotesBytes {
+			log.Printf("Room %s: dropping notes from %s: over %d bytes", msg.RoomID, msg.User, model.MaxNotesBytes)
+			return
+		}
+	case "scorecard_sync":
+		err := json.Unmarshal([]byte(msg.Payload), &card)
+		if err == nil {
+			err = card.Validate()
+		}
+		if err != nil {
+			log.Printf("Room %s: rejecting scorecard from %s: %v", msg.RoomID, msg.User, err)
+			sendReview(msg)
+			return
+		}
+	}
+
+	roomsMu.Lock()
+	doc := getRoom(msg.RoomID)
+	if msg.Type == "notes_sync" {
+		doc.review.Notes = msg.Payload
+	} else {
+		doc.review.Scorecard = card
+		payload, _ := json.Marshal(card)
+		msg.Payload = string(payload)
+	}
+	doc.review.UpdatedAt = time.Now().UnixMilli()
+	if roomStore != nil {
+		review := doc.review
+		roomWrites <- store.RoomWrite{RoomID: msg.RoomID, Review: &review}
+	}
+	roomsMu.Unlock()
+
+	sendTo(interviewerTargets(msg), msg)
+}
+
+// sendReview sends the room's notes and scorecard to msg's sender.
+func sendReview(msg Message) {
+	review, _ := GetRoomReview(msg.RoomID)
+	card, _ := json.Marshal(review.Scorecard)
+	targets := senderTargets(msg)
+	sendTo(targets, Message{Type: "notes_sync", Payload: review.Notes, RoomID: msg.RoomID})
+	sendTo(targets, Message{Type: "scorecard_sync", Payload: string(card), RoomID: msg.RoomID})
+}
+
+// GetRoomReview returns the interviewers' review of roomID, or false if
+// nobody has opened the room yet.
+func GetRoomReview(roomID string) (model.RoomReview, bool) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	doc, ok := rooms[roomID]
+	if !ok {
+		return model.RoomReview{Scorecard: model.NewScorecard()}, false
+	}
+	review := doc.review
+	review.Scorecard.Ratings = append([]model.Rating(nil), review.Scorecard.Ratings...)
+	return review, true
+}
+
+// interviewerTargets is roomTargets narrowed to interviewers.
+func interviewerTargets(msg Message) map[*websocket.Conn]*ClientInfo {
+	targets := roomTargets(msg)
+	for conn, info := range targets {
+		if info.role != model.Interviewer {
+			delete(targets, conn)
+		}
+	}
+	return targets
+}
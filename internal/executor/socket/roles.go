@@ -0,0 +1,147 @@
+package socket
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gorilla/websocket"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// Every participant has the role of their room token:
+//   - interviewers may do everything, and alone may change the problem, kick
+//     users, lock the editor and read private notes
+//   - candidates edit and run the code, unless the editor is locked
+//   - observers only watch; their cursor is still shown
+
+// editTypes are the messages that change the room's code or input.
+var editTypes = map[string]bool{
+	"delta":           true,
+	"file":            true,
+	"entrypoint_sync": true,
+	"language_sync":   true,
+	"input_sync":      true,
+}
+
+// observerDenied are the messages observers may not send besides edits.
+var observerDenied = map[string]bool{
+	"output_sync": true,
+	"run_input":   true,
+}
+
+// interviewerTypes are the messages only interviewers may send.
+var interviewerTypes = map[string]bool{
+	"lock_sync":      true,
+	"kick":           true,
+	"notes_sync":     true,
+	"scorecard_sync": true,
+}
+
+// serverTypes are the messages only the server sends; clients sending them
+// could fake a run or the room's state.
+var serverTypes = map[string]bool{
+	"ack":          true,
+	"full_sync":    true,
+	"problem_sync": true,
+	"run_started":  true,
+	"run_output":   true,
+	"run_finished": true,
+	"user_left":    true,
+}
+
+var kickHooks []func(roomID, username string)
+
+// authorize returns why msg's sender may not send it, or nil. Messages the
+// server generates are always allowed.
+func authorize(msg Message) error {
+	if msg.sender == nil {
+		return nil
+	}
+	switch {
+	case serverTypes[msg.Type]:
+		return errors.New("only the server may send this")
+	case interviewerTypes[msg.Type] && msg.role != model.Interviewer:
+		return errors.New("only interviewers may do this")
+	case (editTypes[msg.Type] || observerDenied[msg.Type]) && msg.role == model.Observer:
+		return errors.New("observers cannot edit")
+	case editTypes[msg.Type] && msg.role == model.Candidate && IsLocked(msg.RoomID):
+		return errors.New("the editor is locked")
+	}
+	return nil
+}
+
+// IsLocked reports whether an interviewer locked the editor of roomID.
+func IsLocked(roomID string) bool {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	doc, ok := rooms[roomID]
+	return ok && doc.Locked
+}
+
+// IsKicked reports whether username was removed from roomID.
+func IsKicked(roomID, username string) bool {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	doc, ok := rooms[roomID]
+	return ok && doc.kicked[username]
+}
+
+// OnKick registers fn to be called, on its own goroutine, whenever a user is
+// kicked from a room.
+func OnKick(fn func(roomID, username string)) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	kickHooks = append(kickHooks, fn)
+}
+
+// kick adds username to the users removed from the room. Callers must hold
+// roomsMu.
+func (doc *roomDocument) kick(username string) {
+	if doc.kicked == nil {
+		doc.kicked = make(map[string]bool)
+	}
+	doc.kicked[username] = true
+}
+
+// handleKick removes the user named by the payload from the room for good:
+// the room, them included, is told, then their connections are closed and
+// they can't connect again. Interviewers can't be kicked.
+func handleKick(msg Message) {
+	username := msg.Payload
+	var conns []*websocket.Conn
+	clientsMu.RLock()
+	for conn, info := range clients {
+		if info.roomID != msg.RoomID || info.username != username {
+			continue
+		}
+		if info.role == model.Interviewer {
+			clientsMu.RUnlock()
+			log.Printf("Room %s: %s cannot kick interviewer %s", msg.RoomID, msg.User, username)
+			return
+		}
+		conns = append(conns, conn)
+	}
+	hooks := kickHooks
+	clientsMu.RUnlock()
+	if username == "" || username == msg.User {
+		return
+	}
+
+	roomsMu.Lock()
+	doc := getRoom(msg.RoomID)
+	doc.kick(username)
+	saveSnapshot(msg.RoomID, doc)
+	roomsMu.Unlock()
+
+	log.Printf("Room %s: %s kicked %s", msg.RoomID, msg.User, username)
+	record(msg)
+	sendTo(senderTargets(msg), msg)
+	sendTo(roomTargets(msg), msg)
+	for _, conn := range conns {
+		conn.Close()
+		removeClient(conn)
+	}
+	for _, fn := range hooks {
+		go fn(msg.RoomID, username)
+	}
+}
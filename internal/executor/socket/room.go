@@ -0,0 +1,542 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/ot"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// roomDocument is the server-side copy of everything a room shares.
+// It is the source of truth handed to every joiner via full_sync, so a room
+// survives even after its last participant has left.
+type roomDocument struct {
+	// Files holds the code by path. Entrypoint is the file that is run;
+	// empty means the language's source file, which then follows the
+	// language when it changes.
+	Files      map[string]string         `json:"files"`
+	Entrypoint string                    `json:"entrypoint,omitempty"`
+	Revision   int                       `json:"revision"`
+	Input      string                    `json:"input"`
+	Output     string                    `json:"output"`
+	Errors     string                    `json:"errors"` // stderr and compiler output of the last run
+	Language   model.ProgrammingLanguage `json:"language"`
+	// ProblemID is the problem the room is bound to, if any.
+	ProblemID string `json:"problemId,omitempty"`
+	// Locked stops candidates editing; see authorize.
+	Locked bool `json:"locked,omitempty"`
+
+	// kicked are the users removed from the room.
+	kicked map[string]bool
+	// review is the interviewers' notes and scorecard; see review.go.
+	review model.RoomReview
+	// history[i] is the change that moved the room from revision base+i to
+	// base+i+1. Changes before base are dropped once no client can still
+	// base a delta on them; see trimHistory.
+	history   []roomChange
+	base      int
+	createdAt int64
+	// runID is the execution whose streamed output Output and Errors hold.
+	runID string
+}
+
+// roomChange is one revision of a room: op edits the file at path, or file
+// changes the file tree.
+type roomChange struct {
+	path string
+	op   *ot.Operation
+	file *model.FileChange
+}
+
+// deltaPayload is the payload of "delta" and "ack" messages.
+type deltaPayload struct {
+	Revision  int           `json:"revision"`
+	Path      string        `json:"path"`
+	Operation *ot.Operation `json:"operation,omitempty"`
+}
+
+// filePayload is the payload of "file" messages. Clients leave Revision
+// out; the server stamps it.
+type filePayload struct {
+	Revision int `json:"revision"`
+	model.FileChange
+}
+
+var (
+	rooms   = make(map[string]*roomDocument)
+	roomsMu sync.Mutex
+	// roomStore persists rooms when set by LoadRooms; nil keeps them in memory only.
+	roomStore store.RoomStore
+)
+
+// LoadRooms makes s the persistence layer for rooms and rehydrates every room
+// it holds: the latest snapshot is loaded and newer deltas are replayed on top.
+func LoadRooms(s store.RoomStore) error {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	roomStore = s
+	go recordEvents(s)
+	go writeRooms(s)
+	saved, err := s.ListRooms()
+	if err != nil {
+		return err
+	}
+	for _, room := range saved {
+		doc, err := loadRoom(s, room)
+		if err != nil {
+			return fmt.Errorf("room %s: %w", room.ID, err)
+		}
+		rooms[room.ID] = doc
+	}
+	log.Printf("Loaded %d rooms from storage", len(saved))
+	return nil
+}
+
+func loadRoom(s store.RoomStore, room model.Room) (*roomDocument, error) {
+	doc := &roomDocument{Language: model.Python3, createdAt: room.CreatedAt}
+	snapshot, err := s.GetSnapshot(room.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	doc.Language = snapshot.Language
+	// Rooms saved before they had several files kept their code, and
+	// edited it, as the language's source file.
+	legacyPath := sourceFile(doc.Language)
+	doc.Files = snapshot.Files
+	if doc.Files == nil {
+		doc.Files = map[string]string{legacyPath: snapshot.Code}
+	}
+	doc.Entrypoint = snapshot.Entrypoint
+	doc.Revision = snapshot.Revision
+	doc.Input = snapshot.Input
+	doc.Output = snapshot.Output
+	doc.Errors = snapshot.Errors
+	doc.ProblemID = snapshot.ProblemID
+	doc.Locked = snapshot.Locked
+	for _, username := range snapshot.Kicked {
+		doc.kick(username)
+	}
+	if doc.review, err = loadReview(s, room.ID); err != nil {
+		return nil, err
+	}
+
+	// Nobody is connected yet, so only the deltas the snapshot misses are
+	// needed, and none of them is kept as history.
+	deltas, err := s.ListDeltas(room.ID, doc.Revision)
+	if err != nil {
+		return nil, err
+	}
+	for _, delta := range deltas {
+		change := roomChange{path: delta.Path, file: delta.File}
+		if change.file == nil {
+			if change.path == "" {
+				change.path = legacyPath
+			}
+			change.op = ot.New()
+			if err := json.Unmarshal(delta.Operation, change.op); err != nil {
+				return nil, fmt.Errorf("revision %d: %w", delta.Revision, err)
+			}
+		}
+		if delta.Revision != doc.Revision+1 {
+			return nil, fmt.Errorf("delta log has a gap before revision %d", delta.Revision)
+		}
+		if err := doc.apply(change); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", delta.Revision, err)
+		}
+		doc.Revision = delta.Revision
+	}
+	doc.base = doc.Revision
+	return doc, nil
+}
+
+// sourceFile returns the file lang's code is written to by default.
+func sourceFile(lang model.ProgrammingLanguage) string {
+	if l, ok := configs.GetLanguage(lang); ok {
+		return l.SourceFile
+	}
+	return "main"
+}
+
+// entrypoint returns the path of the file that is run.
+func (doc *roomDocument) entrypoint() string {
+	if doc.Entrypoint != "" {
+		return doc.Entrypoint
+	}
+	return sourceFile(doc.Language)
+}
+
+// apply applies change to the files, checking that it fits the current file
+// tree. It does not record it in the history.
+func (doc *roomDocument) apply(change roomChange) error {
+	if change.file == nil {
+		content, ok := doc.Files[change.path]
+		if !ok {
+			return fmt.Errorf("no file %s", change.path)
+		}
+		content, err := change.op.Apply(content)
+		if err != nil {
+			return err
+		}
+		doc.Files[change.path] = content
+		return nil
+	}
+
+	file := change.file
+	_, exists := doc.Files[file.Path]
+	switch file.Action {
+	case model.CreateFile:
+		if exists {
+			return fmt.Errorf("file %s already exists", file.Path)
+		}
+		if len(doc.Files) >= model.MaxProjectFiles {
+			return fmt.Errorf("a room has at most %d files", model.MaxProjectFiles)
+		}
+		if len(file.Content) > model.MaxProjectBytes {
+			return fmt.Errorf("file %s exceeds %d bytes", file.Path, model.MaxProjectBytes)
+		}
+		doc.Files[file.Path] = file.Content
+	case model.DeleteFile:
+		if !exists {
+			return fmt.Errorf("no file %s", file.Path)
+		}
+		delete(doc.Files, file.Path)
+		if doc.Entrypoint == file.Path {
+			doc.Entrypoint = ""
+		}
+	case model.RenameFile:
+		if !exists {
+			return fmt.Errorf("no file %s", file.Path)
+		}
+		if _, taken := doc.Files[file.NewPath]; taken {
+			return fmt.Errorf("file %s already exists", file.NewPath)
+		}
+		doc.Files[file.NewPath] = doc.Files[file.Path]
+		delete(doc.Files, file.Path)
+		if doc.Entrypoint == file.Path {
+			doc.Entrypoint = file.NewPath
+		}
+	default:
+		return fmt.Errorf("unknown file action %q", file.Action)
+	}
+	return nil
+}
+
+// saveSnapshot queues the room document to be persisted after the writes
+// already queued. Callers must hold roomsMu.
+func saveSnapshot(roomID string, doc *roomDocument) {
+	if roomStore == nil {
+		return
+	}
+	room := model.Room{ID: roomID, CreatedAt: doc.createdAt, UpdatedAt: time.Now().UnixMilli()}
+	snapshot := doc.snapshot()
+	roomWrites <- store.RoomWrite{RoomID: roomID, Room: &room, Snapshot: &snapshot}
+}
+
+func (doc *roomDocument) snapshot() model.RoomSnapshot {
+	return model.RoomSnapshot{
+		Revision:   doc.Revision,
+		Files:      maps.Clone(doc.Files),
+		Entrypoint: doc.Entrypoint,
+		Input:      doc.Input,
+		Output:     doc.Output,
+		Errors:     doc.Errors,
+		Language:   doc.Language,
+		ProblemID:  doc.ProblemID,
+		Locked:     doc.Locked,
+		Kicked:     slices.Sorted(maps.Keys(doc.kicked)),
+	}
+}
+
+// GetRoomSnapshot returns the current shared state of roomID, or false if
+// nobody has opened the room yet.
+func GetRoomSnapshot(roomID string) (model.RoomSnapshot, bool) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	doc, ok := rooms[roomID]
+	if !ok {
+		return model.RoomSnapshot{}, false
+	}
+	return doc.snapshot(), true
+}
+
+// seedRoom creates the document for roomID from the joiner's local state
+// unless the room already exists, and returns a copy of the current document
+// and whether it was just created. Local files that don't make a valid
+// project are ignored. holder, the connection the copy is sent to, may base
+// its deltas on the copy's revision from now on.
+func seedRoom(roomID string, local *roomDocument, holder *websocket.Conn) (roomDocument, bool) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	doc, ok := rooms[roomID]
+	if !ok {
+		doc = newRoom(roomID)
+		if local != nil {
+			doc.Input = local.Input
+			doc.Output = local.Output
+			doc.Errors = local.Errors
+			doc.Language = local.Language
+			project := model.SourceCode{Files: local.Files, Entrypoint: local.Entrypoint}
+			if err := project.ValidateFiles(); err != nil {
+				log.Printf("Room %s: ignoring the joiner's files: %v", roomID, err)
+			} else if len(local.Files) > 0 {
+				doc.Files = maps.Clone(local.Files)
+				doc.Entrypoint = local.Entrypoint
+			}
+		}
+		if len(doc.Files) == 0 {
+			doc.Files = map[string]string{doc.entrypoint(): ""}
+		}
+		saveSnapshot(roomID, doc)
+	}
+	holdRevision(holder, doc.Revision)
+	copied := *doc
+	copied.Files = maps.Clone(doc.Files)
+	return copied, !ok
+}
+
+// getRoom returns the document for roomID, creating and persisting one with
+// an empty source file if needed. Callers must hold roomsMu.
+func getRoom(roomID string) *roomDocument {
+	doc, ok := rooms[roomID]
+	if !ok {
+		doc = newRoom(roomID)
+		saveSnapshot(roomID, doc)
+	}
+	return doc
+}
+
+// newRoom adds an empty document for roomID to rooms without persisting it.
+// Callers must hold roomsMu.
+func newRoom(roomID string) *roomDocument {
+	doc := &roomDocument{Language: model.Python3, createdAt: time.Now().UnixMilli()}
+	doc.review.Scorecard = model.NewScorecard()
+	doc.Files = map[string]string{doc.entrypoint(): ""}
+	rooms[roomID] = doc
+	return doc
+}
+
+// applyToRoom updates the room document from a relayed message.
+// Messages that don't affect shared state are ignored.
+func applyToRoom(msg Message) error {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	doc := getRoom(msg.RoomID)
+	switch msg.Type {
+	case "input_sync":
+		doc.Input = msg.Payload
+	case "output_sync":
+		doc.Output = msg.Payload
+	case "lock_sync":
+		doc.Locked = msg.Payload == "true"
+	case "entrypoint_sync":
+		if msg.Payload != "" {
+			if _, ok := doc.Files[msg.Payload]; !ok {
+				return fmt.Errorf("no file %s", msg.Payload)
+			}
+		}
+		doc.Entrypoint = msg.Payload
+	case "run_output":
+		var chunk runOutputPayload
+		if err := json.Unmarshal([]byte(msg.Payload), &chunk); err != nil {
+			return fmt.Errorf("invalid run output: %w", err)
+		}
+		doc.startRun(chunk.ExecutionID)
+		if chunk.Stream == "stderr" {
+			doc.Errors += chunk.Data
+		} else {
+			doc.Output += chunk.Data
+		}
+		// Snapshotted once the run finishes rather than on every chunk.
+		return nil
+	case "run_started":
+		var run runStartedPayload
+		if err := json.Unmarshal([]byte(msg.Payload), &run); err != nil {
+			return fmt.Errorf("invalid run start: %w", err)
+		}
+		doc.startRun(run.ExecutionID)
+		return nil
+	case "run_input":
+		var input runInputPayload
+		if err := json.Unmarshal([]byte(msg.Payload), &input); err != nil {
+			return fmt.Errorf("invalid run input: %w", err)
+		}
+		// Echoed into the output, as a terminal would.
+		doc.startRun(input.ExecutionID)
+		doc.Output += input.Data
+		return nil
+	case "run_finished":
+		var run runFinishedPayload
+		if err := json.Unmarshal([]byte(msg.Payload), &run); err != nil {
+			return fmt.Errorf("invalid run result: %w", err)
+		}
+		doc.startRun(run.ExecutionID)
+		doc.Errors = run.CompileOutput + doc.Errors
+	default:
+		return nil
+	}
+	saveSnapshot(msg.RoomID, doc)
+	return nil
+}
+
+// startRun clears the previous run's output when executionID is a new run.
+func (doc *roomDocument) startRun(executionID string) {
+	if executionID != doc.runID {
+		doc.runID = executionID
+		doc.Output = ""
+		doc.Errors = ""
+	}
+}
+
+// applyOperation transforms an operation made against in.Revision over every
+// edit of the same file the server has accepted since, applies it to the
+// file and returns it stamped with the new revision. The operation follows
+// its file through renames, but is rejected if the file was deleted or its
+// revision is older than the history kept.
+func applyOperation(roomID, user string, in deltaPayload) (deltaPayload, error) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	doc := getRoom(roomID)
+	if in.Revision < doc.base || in.Revision > doc.Revision {
+		return deltaPayload{}, fmt.Errorf("revision %d is not in [%d, %d]", in.Revision, doc.base, doc.Revision)
+	}
+
+	path, op := in.Path, in.Operation
+	for _, concurrent := range doc.history[in.Revision-doc.base:] {
+		if file := concurrent.file; file != nil {
+			switch {
+			case file.Path != path:
+			case file.Action == model.RenameFile:
+				path = file.NewPath
+			default:
+				return deltaPayload{}, fmt.Errorf("file %s was %sd meanwhile", path, file.Action)
+			}
+			continue
+		}
+		if concurrent.path != path {
+			continue
+		}
+		var err error
+		if op, _, err = ot.Transform(op, concurrent.op); err != nil {
+			return deltaPayload{}, err
+		}
+	}
+	if err := commit(roomID, user, doc, roomChange{path: path, op: op}); err != nil {
+		return deltaPayload{}, err
+	}
+	return deltaPayload{Revision: doc.Revision, Path: path, Operation: op}, nil
+}
+
+// applyFileChange applies a change to the room's file tree and returns it
+// stamped with the new revision.
+func applyFileChange(roomID, user string, change model.FileChange) (filePayload, error) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	paths := []string{change.Path}
+	if change.Action == model.RenameFile {
+		paths = append(paths, change.NewPath)
+	}
+	for _, path := range paths {
+		if err := job_executor.ValidateFilePath(path); err != nil {
+			return filePayload{}, err
+		}
+	}
+	doc := getRoom(roomID)
+	if err := commit(roomID, user, doc, roomChange{path: change.Path, file: &change}); err != nil {
+		return filePayload{}, err
+	}
+	return filePayload{Revision: doc.Revision, FileChange: change}, nil
+}
+
+// commit applies change as the room's next revision and persists it.
+// Callers must hold roomsMu.
+func commit(roomID, user string, doc *roomDocument, change roomChange) error {
+	if err := doc.apply(change); err != nil {
+		return err
+	}
+	doc.history = append(doc.history, change)
+	doc.Revision++
+	appendDelta(roomID, user, doc)
+	doc.trimHistory(roomID)
+	return nil
+}
+
+// trimHistory drops the changes before the oldest revision a client of the
+// room may still base a delta on. Callers must hold roomsMu.
+func (doc *roomDocument) trimHistory(roomID string) {
+	if oldest := oldestHeldRevision(roomID, doc.Revision); oldest > doc.base {
+		doc.history = doc.history[oldest-doc.base:]
+		doc.base = oldest
+	}
+}
+
+// setLanguage changes the room's language; see changeLanguage.
+func setLanguage(roomID, user string, lang model.ProgrammingLanguage) (*filePayload, error) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	return getRoom(roomID).changeLanguage(roomID, user, lang)
+}
+
+// changeLanguage changes the room's language. When the room runs the
+// language's default source file, that file is renamed to the new
+// language's, and the rename is returned to be relayed. Callers must hold
+// roomsMu.
+func (doc *roomDocument) changeLanguage(roomID, user string, lang model.ProgrammingLanguage) (*filePayload, error) {
+	from := doc.entrypoint()
+	doc.Language = lang
+	saveSnapshot(roomID, doc)
+	to := doc.entrypoint()
+	if doc.Entrypoint != "" || from == to {
+		return nil, nil
+	}
+	if _, ok := doc.Files[from]; !ok {
+		return nil, nil
+	}
+	if _, ok := doc.Files[to]; ok {
+		return nil, nil
+	}
+	change := model.FileChange{Action: model.RenameFile, Path: from, NewPath: to}
+	if err := commit(roomID, user, doc, roomChange{path: from, file: &change}); err != nil {
+		return nil, err
+	}
+	return &filePayload{Revision: doc.Revision, FileChange: change}, nil
+}
+
+// appendDelta queues the newest change of doc to be persisted, snapshotting
+// the room every SnapshotInterval revisions so rehydration replays a
+// bounded tail. Callers must hold roomsMu.
+func appendDelta(roomID, user string, doc *roomDocument) {
+	if roomStore == nil {
+		return
+	}
+	change := doc.history[len(doc.history)-1]
+	delta := model.RoomDelta{Revision: doc.Revision, User: user, Path: change.path, File: change.file, Timestamp: time.Now().UnixMilli()}
+	if change.op != nil {
+		op, err := json.Marshal(change.op)
+		if err != nil {
+			log.Printf("Room %s: failed to encode revision %d: %v", roomID, doc.Revision, err)
+			return
+		}
+		delta.Operation = op
+	}
+	roomWrites <- store.RoomWrite{RoomID: roomID, Delta: &delta}
+	if interval := configs.GetInstance().SnapshotInterval; interval > 0 && doc.Revision%interval == 0 {
+		saveSnapshot(roomID, doc)
+	}
+}
@@ -0,0 +1,240 @@
+package socket
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/ot"
+	"github.com/namnv2496/go-ide-pair/internal/ot/ottest"
+)
+
+// testEdit is an edit of one file.
+type testEdit struct {
+	path string
+	op   *ot.Operation
+}
+
+// testClient follows the browser's protocol: one delta in flight at a
+// time, later local edits queued behind it and remote edits transformed
+// past everything not yet acknowledged.
+type testClient struct {
+	name     string
+	conn     *websocket.Conn // registered in clients, never dialled
+	files    map[string]string
+	revision int
+	pending  []testEdit
+	// inFlight is the delta on its way to the server, as it was sent;
+	// awaitingAck stays set until the server acknowledges it.
+	inFlight    *deltaPayload
+	awaitingAck bool
+	inbox       []deltaPayload // acks have no operation
+}
+
+func (c *testClient) edit(t *testing.T, rng *rand.Rand, paths []string) {
+	t.Helper()
+	path := paths[rng.IntN(len(paths))]
+	op := ottest.RandomOp(rng, c.files[path])
+	var err error
+	if c.files[path], err = op.Apply(c.files[path]); err != nil {
+		t.Fatal(err)
+	}
+	c.pending = append(c.pending, testEdit{path, op})
+	c.send()
+}
+
+func (c *testClient) send() {
+	if !c.awaitingAck && len(c.pending) > 0 {
+		c.inFlight = &deltaPayload{Revision: c.revision, Path: c.pending[0].path, Operation: c.pending[0].op}
+		c.awaitingAck = true
+	}
+}
+
+// deliver hands c's delta to the server, which acknowledges it to c and
+// relays it to everyone else.
+func deliver(t *testing.T, roomID string, c *testClient, clients []*testClient) {
+	t.Helper()
+	out, err := applyOperation(roomID, c.name, *c.inFlight)
+	if err != nil {
+		t.Fatalf("%s's delta was rejected: %v", c.name, err)
+	}
+	holdRevision(c.conn, c.inFlight.Revision)
+	c.inFlight = nil
+	c.inbox = append(c.inbox, deltaPayload{Revision: out.Revision, Path: out.Path})
+	for _, other := range clients {
+		if other != c {
+			other.inbox = append(other.inbox, out)
+		}
+	}
+}
+
+func (c *testClient) receive(t *testing.T) {
+	t.Helper()
+	msg := c.inbox[0]
+	c.inbox = c.inbox[1:]
+	c.revision = msg.Revision
+	if msg.Operation == nil {
+		c.pending = c.pending[1:]
+		c.awaitingAck = false
+		c.send()
+		return
+	}
+	remote := msg.Operation
+	for i, local := range c.pending {
+		if local.path != msg.Path {
+			continue
+		}
+		var err error
+		if c.pending[i].op, remote, err = ot.Transform(local.op, remote); err != nil {
+			t.Fatalf("%s: transform: %v", c.name, err)
+		}
+	}
+	var err error
+	if c.files[msg.Path], err = remote.Apply(c.files[msg.Path]); err != nil {
+		t.Fatalf("%s: apply remote edit: %v", c.name, err)
+	}
+}
+
+// connect registers a connection to roomID that has synced at revision, and
+// removes it when the test ends.
+func connect(t *testing.T, roomID string, revision int) *websocket.Conn {
+	t.Helper()
+	conn := new(websocket.Conn)
+	clientsMu.Lock()
+	clients[conn] = &ClientInfo{roomID: roomID, revision: revision}
+	clientsMu.Unlock()
+	t.Cleanup(func() {
+		clientsMu.Lock()
+		delete(clients, conn)
+		clientsMu.Unlock()
+	})
+	return conn
+}
+
+// TestConcurrentClientsConverge replays random interleavings of several
+// clients editing two files of a room, with deltas and their acks delayed
+// arbitrarily, and checks that every client ends on the server's files.
+func TestConcurrentClientsConverge(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 8))
+	for round := 0; round < 200; round++ {
+		roomID := fmt.Sprintf("converge-%d", round)
+		roomsMu.Lock()
+		main := getRoom(roomID).entrypoint()
+		roomsMu.Unlock()
+		if _, err := applyFileChange(roomID, "setup", model.FileChange{Action: model.CreateFile, Path: "util.py", Content: "x = 1\n"}); err != nil {
+			t.Fatal(err)
+		}
+		paths := []string{main, "util.py"}
+
+		clients := make([]*testClient, 2+rng.IntN(3))
+		for i := range clients {
+			clients[i] = &testClient{
+				name:     fmt.Sprintf("user%d", i),
+				conn:     connect(t, roomID, 1),
+				files:    map[string]string{main: "", "util.py": "x = 1\n"},
+				revision: 1,
+			}
+		}
+
+		for step := 0; step < 300; step++ {
+			c := clients[rng.IntN(len(clients))]
+			switch action := rng.IntN(3); {
+			case action == 0 && step < 200:
+				c.edit(t, rng, paths)
+			case action == 1 && c.inFlight != nil:
+				deliver(t, roomID, c, clients)
+			case action == 2 && len(c.inbox) > 0:
+				c.receive(t)
+			}
+		}
+
+		// Deliver everything still on its way.
+		for busy := true; busy; {
+			busy = false
+			for _, c := range clients {
+				if c.inFlight != nil {
+					deliver(t, roomID, c, clients)
+					busy = true
+				}
+				for len(c.inbox) > 0 {
+					c.receive(t)
+					busy = true
+				}
+			}
+		}
+
+		roomsMu.Lock()
+		server := getRoom(roomID).Files
+		roomsMu.Unlock()
+		for _, c := range clients {
+			for _, path := range paths {
+				if c.files[path] != server[path] {
+					t.Fatalf("round %d: %s has %s = %q, the server %q", round, c.name, path, c.files[path], server[path])
+				}
+			}
+		}
+	}
+}
+
+func TestApplyOperationRejectsUnknownRevision(t *testing.T) {
+	roomsMu.Lock()
+	path := getRoom("revision-check").entrypoint()
+	roomsMu.Unlock()
+	if _, err := applyOperation("revision-check", "user", deltaPayload{Revision: 5, Path: path, Operation: ot.New().Insert("a")}); err == nil {
+		t.Error("a delta based on a future revision was applied")
+	}
+}
+
+func TestHistoryKeepsWhatClientsHold(t *testing.T) {
+	roomsMu.Lock()
+	path := getRoom("history-check").entrypoint()
+	roomsMu.Unlock()
+	conn := connect(t, "history-check", 0)
+	for i := 0; i < 3; i++ {
+		if _, err := applyOperation("history-check", "a", deltaPayload{Revision: i, Path: path, Operation: ot.New().Retain(i).Insert("a")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	roomsMu.Lock()
+	kept := len(rooms["history-check"].history)
+	roomsMu.Unlock()
+	if kept != 3 {
+		t.Fatalf("kept %d changes for a client at revision 0, want 3", kept)
+	}
+
+	holdRevision(conn, 2)
+	if _, err := applyOperation("history-check", "a", deltaPayload{Revision: 3, Path: path, Operation: ot.New().Retain(3).Insert("a")}); err != nil {
+		t.Fatal(err)
+	}
+	roomsMu.Lock()
+	doc := rooms["history-check"]
+	base, kept := doc.base, len(doc.history)
+	roomsMu.Unlock()
+	if base != 2 || kept != 2 {
+		t.Errorf("kept %d changes from revision %d, want 2 from revision 2", kept, base)
+	}
+	if _, err := applyOperation("history-check", "a", deltaPayload{Revision: 1, Path: path, Operation: ot.New().Retain(1)}); err == nil {
+		t.Error("a delta based on a trimmed revision was applied")
+	}
+}
+
+func TestApplyOperationFollowsRenames(t *testing.T) {
+	roomsMu.Lock()
+	path := getRoom("rename-check").entrypoint()
+	roomsMu.Unlock()
+	connect(t, "rename-check", 0)
+	if _, err := applyFileChange("rename-check", "a", model.FileChange{Action: model.RenameFile, Path: path, NewPath: "renamed.py"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := applyOperation("rename-check", "b", deltaPayload{Revision: 0, Path: path, Operation: ot.New().Insert("print(1)")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	if out.Path != "renamed.py" || rooms["rename-check"].Files["renamed.py"] != "print(1)" {
+		t.Errorf("edit landed on %s; files are %v", out.Path, rooms["rename-check"].Files)
+	}
+}
@@ -0,0 +1,35 @@
+package socket
+
+import (
+	"log"
+
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
+
+// maxWriteBatch bounds how many room writes share a transaction.
+const maxWriteBatch = 1024
+
+// roomWrites decouples persisting rooms from HandleMessages, like
+// recordings: an edit is relayed without waiting for the disk, and the
+// writer commits everything queued meanwhile in one transaction. Unlike
+// events, writes are never dropped — a missing delta would break
+// rehydration — so a full queue makes the sender wait.
+var roomWrites = make(chan store.RoomWrite, 4096)
+
+func writeRooms(s store.RoomStore) {
+	for w := range roomWrites {
+		batch := []store.RoomWrite{w}
+	drain:
+		for len(batch) < maxWriteBatch {
+			select {
+			case w := <-roomWrites:
+				batch = append(batch, w)
+			default:
+				break drain
+			}
+		}
+		if err := s.WriteRooms(batch); err != nil {
+			log.Printf("Failed to persist %d room changes: %v", len(batch), err)
+		}
+	}
+}
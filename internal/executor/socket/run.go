@@ -0,0 +1,134 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// runOutputPayload is the payload of "run_output" messages.
+type runOutputPayload struct {
+	ExecutionID string `json:"executionId"`
+	Stream      string `json:"stream"`
+	Data        string `json:"data"`
+}
+
+// runStartedPayload is the payload of "run_started" messages.
+type runStartedPayload struct {
+	ExecutionID string `json:"executionId"`
+	Interactive bool   `json:"interactive"`
+}
+
+// runInputPayload is the payload of "run_input" messages: input typed into
+// an interactive run. EOF ends the program's stdin.
+type runInputPayload struct {
+	ExecutionID string `json:"executionId"`
+	Data        string `json:"data"`
+	EOF         bool   `json:"eof,omitempty"`
+}
+
+// runInput is the stdin of an interactive run of a room.
+type runInput struct {
+	roomID string
+	buffer *job_executor.InputBuffer
+}
+
+var (
+	runInputs   = make(map[string]*runInput)
+	runInputsMu sync.Mutex
+)
+
+// OpenRunInput returns the stdin of interactive execution executionID, fed
+// by the "run_input" messages of roomID until CloseRunInput.
+func OpenRunInput(roomID, executionID string) io.Reader {
+	in := &runInput{roomID: roomID, buffer: job_executor.NewInputBuffer()}
+	runInputsMu.Lock()
+	runInputs[executionID] = in
+	runInputsMu.Unlock()
+	return in.buffer
+}
+
+// CloseRunInput ends the stdin of executionID once it has finished.
+func CloseRunInput(executionID string) {
+	runInputsMu.Lock()
+	in := runInputs[executionID]
+	delete(runInputs, executionID)
+	runInputsMu.Unlock()
+	if in != nil {
+		in.buffer.Close()
+	}
+}
+
+// handleRunInput passes a "run_input" message to its execution's stdin.
+func handleRunInput(msg Message) error {
+	var input runInputPayload
+	if err := json.Unmarshal([]byte(msg.Payload), &input); err != nil {
+		return err
+	}
+	runInputsMu.Lock()
+	in := runInputs[input.ExecutionID]
+	runInputsMu.Unlock()
+	if in == nil || in.roomID != msg.RoomID {
+		return errors.New("no interactive run with that ID in the room")
+	}
+	if _, err := in.buffer.Write([]byte(input.Data)); err != nil {
+		return err
+	}
+	if input.EOF {
+		in.buffer.Close()
+	}
+	return nil
+}
+
+// runFinishedPayload is the payload of "run_finished" messages.
+type runFinishedPayload struct {
+	ExecutionID     string                 `json:"executionId"`
+	Status          model.ExecutionStatus  `json:"status"`
+	ExitCode        int                    `json:"exitCode"`
+	RunTime         int64                  `json:"runTime"`
+	CPUTime         int64                  `json:"cpuTime"`
+	CompileTime     int64                  `json:"compileTime"`
+	PeakMemory      int64                  `json:"peakMemory"`
+	CompileOutput   string                 `json:"compileOutput"`
+	StdoutTruncated bool                   `json:"stdoutTruncated"`
+	StderrTruncated bool                   `json:"stderrTruncated"`
+	TestCases       []model.TestCaseResult `json:"testCases"`
+	Verdict         model.Verdict          `json:"verdict"`
+}
+
+// BroadcastRunStarted tells everyone in roomID that an execution has started,
+// so they can type into it when it is interactive.
+func BroadcastRunStarted(roomID, executionID string, interactive bool) {
+	payload, _ := json.Marshal(runStartedPayload{ExecutionID: executionID, Interactive: interactive})
+	broadcast <- Message{Type: "run_started", Payload: string(payload), RoomID: roomID}
+}
+
+// BroadcastRunOutput sends a chunk of a running program's output to everyone
+// in roomID.
+func BroadcastRunOutput(roomID, executionID string, chunk job_executor.OutputChunk) {
+	payload, _ := json.Marshal(runOutputPayload{ExecutionID: executionID, Stream: chunk.Stream, Data: chunk.Data})
+	broadcast <- Message{Type: "run_output", Payload: string(payload), RoomID: roomID}
+}
+
+// BroadcastRunFinished tells everyone in roomID that an execution has ended.
+func BroadcastRunFinished(roomID string, exec model.Execution) {
+	payload, _ := json.Marshal(runFinishedPayload{
+		ExecutionID:     exec.ID,
+		Status:          exec.Status,
+		ExitCode:        exec.ExitCode,
+		RunTime:         exec.RunTime,
+		CPUTime:         exec.CPUTime,
+		CompileTime:     exec.CompileTime,
+		PeakMemory:      exec.PeakMemory,
+		CompileOutput:   exec.CompileOutput,
+		StdoutTruncated: exec.StdoutTruncated,
+		StderrTruncated: exec.StderrTruncated,
+		TestCases:       exec.TestCases,
+		Verdict:         exec.Verdict,
+	})
+	broadcast <- Message{Type: "run_finished", Payload: string(payload), RoomID: roomID}
+}
@@ -1,43 +1,111 @@
 package socket
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"sync"
 
 	"github.com/gorilla/websocket"
+	"github.com/namnv2496/go-ide-pair/internal/auth"
+	"github.com/namnv2496/go-ide-pair/internal/model"
 )
 
 // ClientInfo holds metadata for a connected WebSocket client.
 type ClientInfo struct {
 	username string
 	roomID   string
+	role     model.Role
+	// revision is the oldest revision the client may still base a delta on:
+	// that of its last full_sync or accepted delta, or -1 before it synced.
+	revision int
 }
 
 // Message is the envelope for all WebSocket messages.
 //
 // Type values:
-//   - "delta"        — an Ace editor delta (payload = JSON-encoded delta object)
-//   - "full_sync"    — full document content sent to a new joiner (payload = document text)
-//   - "request_sync" — sent by a new joiner to ask existing clients for full_sync
-//   - "stop"         — client is disconnecting
+//   - "delta"         — an edit to the file at the payload's path (payload =
+//     JSON-encoded deltaPayload). Clients send the revision their operation is
+//     based on; the server transforms it against anything newer, stamps the
+//     new revision and relays the transformed operation
+//   - "ack"           — sent by the server to the author of a delta once it is
+//     applied (payload = JSON-encoded deltaPayload without an operation; the
+//     path is the file's current one)
+//   - "file"          — creates, deletes or renames a file (payload =
+//     JSON-encoded filePayload). It takes a revision like a delta; the server
+//     sends the stamped change to the whole room, author included
+//   - "entrypoint_sync" — the file that is run changed (payload = its path,
+//     empty for the language's source file)
+//   - "input_sync"    — the shared stdin textarea changed (payload = input text)
+//   - "output_sync"   — the shared output textarea changed (payload = output text)
+//   - "language_sync" — the selected language changed (payload = model.ProgrammingLanguage).
+//     When the room runs the language's default source file, the server
+//     follows it with a "file" message renaming that file
+//   - "request_sync"  — sent by a joiner; payload is its local document, used to
+//     seed the room only if the server has no document for it yet
+//   - "full_sync"     — sent by the server in reply to request_sync
+//     (payload = JSON-encoded roomDocument)
+//   - "run_started"   — sent by the server when a room's program starts
+//     (payload = JSON-encoded runStartedPayload)
+//   - "run_output"    — sent by the server while a room's program runs
+//     (payload = JSON-encoded runOutputPayload)
+//   - "run_finished"  — sent by the server when that program exits
+//     (payload = JSON-encoded runFinishedPayload)
+//   - "run_input"     — input typed into a room's interactive run (payload =
+//     JSON-encoded runInputPayload); passed to its stdin and relayed so the
+//     room sees it like a terminal echo
+//   - "problem_sync"  — sent by the server when the room is bound to a problem
+//     (payload = JSON-encoded problemSyncPayload); the starter code arrives
+//     as a delta just before it
+//   - "lock_sync"     — the editor was locked or unlocked (payload = "true" or
+//     "false"); interviewers only. Candidates can't edit while it is locked
+//   - "kick"          — removes a user from the room for good (payload = their
+//     username); interviewers only. Sent to the whole room, the kicked user
+//     included, before their connections are closed
+//   - "notes_sync"    — the interviewers' private notes changed (payload =
+//     markdown); interviewers only, and only ever sent to interviewers, who
+//     also get it after their full_sync. Never recorded
+//   - "scorecard_sync" — the interviewers' scorecard changed (payload =
+//     JSON-encoded model.Scorecard); sent like notes_sync. A scorecard that
+//     doesn't validate is answered with the current one
+//   - "stop"          — client is disconnecting
 type Message struct {
 	Type    string `json:"type"`
 	Payload string `json:"payload"`
 	User    string `json:"user"`
 	RoomID  string `json:"roomId"`
+
+	// sender is the connection the message arrived on; nil for messages the
+	// server generates itself. role is the sender's role.
+	sender *websocket.Conn
+	role   model.Role
 }
 
 var (
 	clients   = make(map[*websocket.Conn]*ClientInfo)
 	clientsMu sync.RWMutex
-	broadcast = make(chan Message, 256)
-	upgrader  = websocket.Upgrader{
+	// roomEmptyHooks are called when the last connection to a room closes.
+	roomEmptyHooks []func(roomID string)
+	broadcast      = make(chan Message, 256)
+	upgrader       = websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
 	}
 )
 
+// HandleConnections serves /ws?token=, where token is a room token: who
+// connects, to which room and in which role come from it.
 func HandleConnections(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.FromRequest(r, auth.RoomToken)
+	if err != nil {
+		log.Println("Rejected connection:", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != model.Interviewer && IsKicked(claims.Room, claims.Subject) {
+		log.Printf("Rejected connection: %s was kicked from room %s", claims.Subject, claims.Room)
+		http.Error(w, "you were removed from this room", http.StatusForbidden)
+		return
+	}
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("WebSocket upgrade error:", err)
@@ -45,73 +113,333 @@ func HandleConnections(w http.ResponseWriter, r *http.Request) {
 	}
 	defer ws.Close()
 
-	username := r.URL.Query().Get("username")
-	roomID := r.URL.Query().Get("room")
-	if username == "" || roomID == "" {
-		log.Println("Rejected connection: missing username or room query param")
-		return
-	}
+	username := claims.Subject
+	roomID := claims.Room
+	role := claims.Role
 
-	log.Printf("Connected: %s → room %s", username, roomID)
+	log.Printf("Connected: %s → room %s as %s", username, roomID, role)
 	clientsMu.Lock()
-	clients[ws] = &ClientInfo{username: username, roomID: roomID}
+	clients[ws] = &ClientInfo{username: username, roomID: roomID, role: role, revision: -1}
 	clientsMu.Unlock()
 
 	for {
 		var msg Message
 		if err := ws.ReadJSON(&msg); err != nil {
 			log.Printf("Disconnected: %s (%v)", username, err)
-			clientsMu.Lock()
-			delete(clients, ws)
-			clientsMu.Unlock()
+			removeClient(ws)
 			// Notify remaining room members that this user left.
 			broadcast <- Message{Type: "user_left", User: username, RoomID: roomID}
 			break
 		}
-		// Overwrite user/room from the authenticated query params — never trust the client fields.
+		// Overwrite user/room from the verified token — never trust the client fields.
 		msg.User = username
 		msg.RoomID = roomID
+		msg.sender = ws
+		msg.role = role
 		broadcast <- msg
 	}
 }
 
+// RoomSize returns the number of connections to roomID.
+func RoomSize(roomID string) int {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	n := 0
+	for _, info := range clients {
+		if info.roomID == roomID {
+			n++
+		}
+	}
+	return n
+}
+
+// OnRoomEmpty registers fn to be called, on its own goroutine, whenever the
+// last connection to a room goes away.
+func OnRoomEmpty(fn func(roomID string)) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	roomEmptyHooks = append(roomEmptyHooks, fn)
+}
+
+// removeClient forgets conn and runs the room-empty hooks if it was the last
+// connection to its room. Removing a connection twice is harmless.
+func removeClient(conn *websocket.Conn) {
+	clientsMu.Lock()
+	info, ok := clients[conn]
+	delete(clients, conn)
+	empty := ok
+	if ok {
+		for _, other := range clients {
+			if other.roomID == info.roomID {
+				empty = false
+				break
+			}
+		}
+	}
+	hooks := roomEmptyHooks
+	clientsMu.Unlock()
+
+	if empty {
+		for _, fn := range hooks {
+			go fn(info.roomID)
+		}
+	}
+}
+
 func HandleMessages() {
 	for msg := range broadcast {
 		if msg.Type == "stop" {
-			clientsMu.Lock()
+			var leaving *websocket.Conn
+			clientsMu.RLock()
 			for conn, info := range clients {
 				if info.username == msg.User && info.roomID == msg.RoomID {
-					log.Printf("Disconnecting %s from room %s", msg.User, msg.RoomID)
-					conn.Close()
-					delete(clients, conn)
+					leaving = conn
 					break
 				}
 			}
-			clientsMu.Unlock()
+			clientsMu.RUnlock()
+			if leaving != nil {
+				log.Printf("Disconnecting %s from room %s", msg.User, msg.RoomID)
+				leaving.Close()
+				removeClient(leaving)
+			}
 			// Notify remaining room members that this user left.
 			broadcast <- Message{Type: "user_left", User: msg.User, RoomID: msg.RoomID}
 			continue
 		}
 
-		// Snapshot everyone in the same room except the sender, then write
-		// outside the lock so a slow write doesn't block other goroutines.
-		clientsMu.RLock()
-		targets := make(map[*websocket.Conn]*ClientInfo)
-		for conn, info := range clients {
-			if info.roomID == msg.RoomID && info.username != msg.User {
-				targets[conn] = info
+		if err := authorize(msg); err != nil {
+			log.Printf("Room %s: dropping %s from %s: %v", msg.RoomID, msg.Type, msg.User, err)
+			if editTypes[msg.Type] {
+				// The sender already applied its edit; undo it.
+				sendFullSync(Message{Type: "request_sync", User: msg.User, RoomID: msg.RoomID, sender: msg.sender})
 			}
+			continue
+		}
+		if msg.Type == "kick" {
+			handleKick(msg)
+			continue
 		}
-		clientsMu.RUnlock()
 
-		for conn, info := range targets {
-			if err := conn.WriteJSON(msg); err != nil {
-				log.Printf("Write error to %s: %v", info.username, err)
-				clientsMu.Lock()
-				conn.Close()
-				delete(clients, conn)
-				clientsMu.Unlock()
+		if msg.Type == "request_sync" {
+			// The server owns the room document, so it answers directly
+			// instead of relying on another participant to respond.
+			if msg.role == model.Observer {
+				// Observers don't get to seed the room either.
+				msg.Payload = ""
+			}
+			sendFullSync(msg)
+			if msg.role == model.Interviewer {
+				sendReview(msg)
+			}
+			continue
+		}
+		if reviewTypes[msg.Type] {
+			handleReview(msg)
+			continue
+		}
+		if msg.Type == "delta" {
+			handleDelta(msg)
+			continue
+		}
+		if msg.Type == "file" {
+			handleFile(msg)
+			continue
+		}
+		if msg.Type == "language_sync" {
+			handleLanguageSync(msg)
+			continue
+		}
+		if msg.Type == "problem_sync" {
+			handleProblemSync(msg)
+			continue
+		}
+		if msg.Type == "run_input" {
+			if err := handleRunInput(msg); err != nil {
+				log.Printf("Room %s: dropping run_input from %s: %v", msg.RoomID, msg.User, err)
+				continue
 			}
 		}
+		if err := applyToRoom(msg); err != nil {
+			log.Printf("Room %s: dropping %s from %s: %v", msg.RoomID, msg.Type, msg.User, err)
+			continue
+		}
+
+		record(msg)
+		sendTo(roomTargets(msg), msg)
+	}
+}
+
+// handleDelta transforms an incoming operation against the room history,
+// acknowledges it to its author and relays the transformed operation.
+// An operation that cannot be applied means the author has diverged, so it
+// is resynchronised with a full_sync instead.
+func handleDelta(msg Message) {
+	var in deltaPayload
+	if err := json.Unmarshal([]byte(msg.Payload), &in); err != nil || in.Operation == nil {
+		log.Printf("Room %s: malformed delta from %s: %v", msg.RoomID, msg.User, err)
+		sendFullSync(msg)
+		return
+	}
+	out, err := applyOperation(msg.RoomID, msg.User, in)
+	if err != nil {
+		log.Printf("Room %s: rejecting delta from %s: %v", msg.RoomID, msg.User, err)
+		sendFullSync(msg)
+		return
+	}
+	holdRevision(msg.sender, in.Revision)
+
+	ack, _ := json.Marshal(deltaPayload{Revision: out.Revision, Path: out.Path})
+	sendTo(senderTargets(msg), Message{Type: "ack", Payload: string(ack), RoomID: msg.RoomID})
+
+	relayed, _ := json.Marshal(out)
+	msg.Payload = string(relayed)
+	record(msg)
+	sendTo(roomTargets(msg), msg)
+}
+
+// handleFile applies a change to the file tree and sends it, stamped, to the
+// whole room. A change that doesn't fit the tree resynchronises its author.
+func handleFile(msg Message) {
+	var in filePayload
+	if err := json.Unmarshal([]byte(msg.Payload), &in); err != nil {
+		log.Printf("Room %s: malformed file change from %s: %v", msg.RoomID, msg.User, err)
+		return
+	}
+	out, err := applyFileChange(msg.RoomID, msg.User, in.FileChange)
+	if err != nil {
+		log.Printf("Room %s: rejecting file change from %s: %v", msg.RoomID, msg.User, err)
+		sendFullSync(msg)
+		return
+	}
+	payload, _ := json.Marshal(out)
+	msg.Payload = string(payload)
+	record(msg)
+	sendTo(senderTargets(msg), msg)
+	sendTo(roomTargets(msg), msg)
+}
+
+// handleLanguageSync relays a language change, followed by the rename of the
+// room's source file when it follows the language.
+func handleLanguageSync(msg Message) {
+	var lang model.ProgrammingLanguage
+	if err := json.Unmarshal([]byte(msg.Payload), &lang); err != nil {
+		log.Printf("Room %s: dropping language_sync from %s: %v", msg.RoomID, msg.User, err)
+		return
+	}
+	rename, err := setLanguage(msg.RoomID, msg.User, lang)
+	record(msg)
+	sendTo(roomTargets(msg), msg)
+	if err != nil {
+		log.Printf("Room %s: failed to rename the source file: %v", msg.RoomID, err)
+		return
+	}
+	if rename != nil {
+		payload, _ := json.Marshal(rename)
+		renameMsg := Message{Type: "file", Payload: string(payload), User: msg.User, RoomID: msg.RoomID, sender: msg.sender}
+		record(renameMsg)
+		sendTo(senderTargets(renameMsg), renameMsg)
+		sendTo(roomTargets(renameMsg), renameMsg)
+	}
+}
+
+// holdRevision records that conn may base its deltas on revision from now
+// on, so the room keeps the history after it.
+func holdRevision(conn *websocket.Conn, revision int) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if info, ok := clients[conn]; ok {
+		info.revision = revision
+	}
+}
+
+// oldestHeldRevision returns the oldest revision a client of roomID may still
+// base a delta on, or latest if none may base one on an older revision.
+func oldestHeldRevision(roomID string, latest int) int {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	oldest := latest
+	for _, info := range clients {
+		if info.roomID == roomID && info.revision >= 0 {
+			oldest = min(oldest, info.revision)
+		}
+	}
+	return oldest
+}
+
+// roomTargets snapshots everyone in msg's room except the sender. When the
+// message came from a connection only that connection is skipped, so a user
+// with two tabs open still sees their own edits in the other tab.
+func roomTargets(msg Message) map[*websocket.Conn]*ClientInfo {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+
+	targets := make(map[*websocket.Conn]*ClientInfo)
+	for conn, info := range clients {
+		if info.roomID != msg.RoomID {
+			continue
+		}
+		if msg.sender != nil && conn == msg.sender {
+			continue
+		}
+		if msg.sender == nil && info.username == msg.User {
+			continue
+		}
+		targets[conn] = info
+	}
+	return targets
+}
+
+// senderTargets returns the connection msg arrived on, or every connection
+// of msg's user in the room for server-generated messages.
+func senderTargets(msg Message) map[*websocket.Conn]*ClientInfo {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+
+	targets := make(map[*websocket.Conn]*ClientInfo)
+	for conn, info := range clients {
+		if msg.sender != nil && conn == msg.sender {
+			targets[conn] = info
+		}
+		if msg.sender == nil && info.roomID == msg.RoomID && info.username == msg.User {
+			targets[conn] = info
+		}
+	}
+	return targets
+}
+
+// sendTo writes msg to every target outside the clients lock so a slow write
+// doesn't block other goroutines. Connections that fail are dropped.
+func sendTo(targets map[*websocket.Conn]*ClientInfo, msg Message) {
+	for conn, info := range targets {
+		if err := conn.WriteJSON(msg); err != nil {
+			log.Printf("Write error to %s: %v", info.username, err)
+			conn.Close()
+			removeClient(conn)
+		}
+	}
+}
+
+// sendFullSync replies to a request_sync with the room's current document.
+// The requester's payload seeds the room when nobody has opened it before.
+func sendFullSync(msg Message) {
+	var local *roomDocument
+	if msg.Payload != "" {
+		local = &roomDocument{}
+		if err := json.Unmarshal([]byte(msg.Payload), local); err != nil {
+			log.Printf("Room %s: ignoring malformed request_sync from %s: %v", msg.RoomID, msg.User, err)
+			local = nil
+		}
+	}
+	doc, created := seedRoom(msg.RoomID, local, msg.sender)
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		log.Printf("Room %s: failed to encode document: %v", msg.RoomID, err)
+		return
+	}
+	reply := Message{Type: "full_sync", Payload: string(payload), User: msg.User, RoomID: msg.RoomID}
+	if created {
+		record(reply)
 	}
+	sendTo(senderTargets(msg), reply)
 }
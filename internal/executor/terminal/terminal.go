@@ -0,0 +1,437 @@
+package terminal
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/namnv2496/go-ide-pair/internal/auth"
+	"github.com/namnv2496/go-ide-pair/internal/executor/socket"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// Every room can have one shared terminal: a shell on a PTY in a long-lived
+// sandbox, started when someone first opens it with the room's code in its
+// working directory. Everyone connected sees the same screen. Interviewers
+// always may type, and alone grant or revoke typing to candidates; the first
+// candidate to open it is granted it. Candidates can't type while the editor
+// is locked, and observers never type. It is destroyed when the shell exits,
+// or once nobody is left in the room and nobody has it open.
+
+// Message is the envelope for terminal WebSocket messages.
+//
+// Type values sent by the server:
+//   - "output" — what the terminal displays (payload = text)
+//   - "rights" — who may type (payload = JSON-encoded rightsPayload); sent on
+//     connect and whenever rights change
+//   - "exit"   — the shell exited; the next connection starts a new one
+//   - "error"  — the terminal could not be started (payload = reason)
+//
+// Type values sent by clients:
+//   - "input"  — keystrokes (payload = text); dropped unless the sender may type
+//   - "resize" — the terminal size (payload = JSON-encoded resizePayload); from
+//     users who may type only, so the screen follows whoever is typing
+//   - "grant"  — lets a user type (payload = username); interviewers only
+//   - "revoke" — stops a user typing (payload = username); interviewers only
+type Message struct {
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+	User    string `json:"user,omitempty"`
+}
+
+// rightsPayload is the payload of "rights" messages: the candidates granted
+// typing. Interviewers aren't listed since they always may type.
+type rightsPayload struct {
+	Writers []string `json:"writers"`
+}
+
+// resizePayload is the payload of "resize" messages.
+type resizePayload struct {
+	Rows uint `json:"rows"`
+	Cols uint `json:"cols"`
+}
+
+// maxScrollback is how much recent output a joiner is shown.
+const maxScrollback = 64 << 10
+
+// session is the terminal of one room.
+type session struct {
+	roomID string
+	// ready is closed once term or err is set.
+	ready chan struct{}
+	term  job_executor.Terminal
+	err   error
+
+	mu sync.Mutex
+	// writers are the candidates granted typing.
+	writers    map[string]bool
+	conns      map[*websocket.Conn]*conn
+	scrollback []byte
+	closed     bool
+}
+
+// conn is one connection to a session. Writes are serialised per connection
+// since gorilla/websocket allows only one concurrent writer.
+type conn struct {
+	ws       *websocket.Conn
+	username string
+	role     model.Role
+	mu       sync.Mutex
+}
+
+func (c *conn) send(msg Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ws.WriteJSON(msg); err != nil {
+		c.ws.Close()
+	}
+}
+
+var (
+	sessions   = make(map[string]*session)
+	sessionsMu sync.Mutex
+	upgrader   = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+)
+
+func init() {
+	socket.OnRoomEmpty(func(roomID string) {
+		sessionsMu.Lock()
+		s := sessions[roomID]
+		sessionsMu.Unlock()
+		if s != nil {
+			s.closeIfUnused()
+		}
+	})
+	socket.OnKick(func(roomID, username string) {
+		sessionsMu.Lock()
+		s := sessions[roomID]
+		sessionsMu.Unlock()
+		if s != nil {
+			s.kick(username)
+		}
+	})
+}
+
+// HandleConnections serves /terminal?token=, where token is a room token.
+func HandleConnections(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.FromRequest(r, auth.RoomToken)
+	if err != nil {
+		log.Println("Rejected terminal connection:", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != model.Interviewer && socket.IsKicked(claims.Room, claims.Subject) {
+		http.Error(w, "you were removed from this room", http.StatusForbidden)
+		return
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("WebSocket upgrade error:", err)
+		return
+	}
+	defer ws.Close()
+
+	username := claims.Subject
+	roomID := claims.Room
+
+	c := &conn{ws: ws, username: username, role: claims.Role}
+	s, err := join(roomID, c)
+	if err != nil {
+		log.Printf("Room %s: failed to open terminal: %v", roomID, err)
+		c.send(Message{Type: "error", Payload: err.Error()})
+		return
+	}
+	defer s.leave(c)
+
+	for {
+		var msg Message
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		s.handle(c, msg)
+	}
+}
+
+// join adds c to the terminal of roomID, starting it if needed, and sends c
+// the current rights and recent output.
+func join(roomID string, c *conn) (*session, error) {
+	sessionsMu.Lock()
+	s, ok := sessions[roomID]
+	if !ok {
+		s = &session{
+			roomID:  roomID,
+			ready:   make(chan struct{}),
+			writers: make(map[string]bool),
+			conns:   make(map[*websocket.Conn]*conn),
+		}
+		sessions[roomID] = s
+	}
+	sessionsMu.Unlock()
+
+	if !ok {
+		s.start()
+	}
+	<-s.ready
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errors.New("the terminal has exited")
+	}
+	s.conns[c.ws] = c
+	// A candidate opening a terminal nobody may type in yet may use it.
+	granted := c.role == model.Candidate && len(s.writers) == 0 && !s.hasInterviewerLocked()
+	if granted {
+		s.writers[c.username] = true
+	}
+	scrollback := string(s.scrollback)
+	rights := s.rightsLocked()
+	s.mu.Unlock()
+
+	if granted {
+		s.broadcast(rights)
+	} else {
+		c.send(rights)
+	}
+	if scrollback != "" {
+		c.send(Message{Type: "output", Payload: scrollback})
+	}
+	return s, nil
+}
+
+// start opens the terminal with the room's files in its working directory.
+func (s *session) start() {
+	var files map[string]string
+	if snapshot, ok := socket.GetRoomSnapshot(s.roomID); ok {
+		files = snapshot.Files
+	}
+	s.term, s.err = worker.OpenTerminal(files)
+	if s.err != nil {
+		sessionsMu.Lock()
+		delete(sessions, s.roomID)
+		sessionsMu.Unlock()
+	} else {
+		log.Printf("Room %s: terminal started", s.roomID)
+		go s.pump()
+	}
+	close(s.ready)
+}
+
+// pump relays the terminal's output to every connection until the shell
+// exits.
+func (s *session) pump() {
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := s.term.Read(buf)
+		if n > 0 {
+			data := append(pending, buf[:n]...)
+			cut := completeUTF8(data)
+			pending = append([]byte(nil), data[cut:]...)
+			if cut > 0 {
+				s.output(string(data[:cut]))
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !s.isClosed() {
+				log.Printf("Room %s: terminal read error: %v", s.roomID, err)
+			}
+			break
+		}
+	}
+	s.broadcast(Message{Type: "exit"})
+	s.close()
+}
+
+// completeUTF8 returns the length of data without an incomplete trailing
+// rune, so output split across reads is only sent as valid strings.
+func completeUTF8(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+func (s *session) output(data string) {
+	s.mu.Lock()
+	s.scrollback = append(s.scrollback, data...)
+	if extra := len(s.scrollback) - maxScrollback; extra > 0 {
+		s.scrollback = s.scrollback[extra:]
+		// Don't start the scrollback halfway through a rune.
+		for len(s.scrollback) > 0 && !utf8.RuneStart(s.scrollback[0]) {
+			s.scrollback = s.scrollback[1:]
+		}
+	}
+	s.mu.Unlock()
+	s.broadcast(Message{Type: "output", Payload: data})
+}
+
+func (s *session) handle(c *conn, msg Message) {
+	switch msg.Type {
+	case "input":
+		if !s.canType(c) {
+			return
+		}
+		if _, err := io.WriteString(s.term, msg.Payload); err != nil {
+			log.Printf("Room %s: terminal write error: %v", s.roomID, err)
+		}
+	case "resize":
+		var size resizePayload
+		if err := json.Unmarshal([]byte(msg.Payload), &size); err != nil || size.Rows == 0 || size.Cols == 0 {
+			return
+		}
+		if !s.canType(c) {
+			return
+		}
+		if err := s.term.Resize(size.Rows, size.Cols); err != nil {
+			log.Printf("Room %s: terminal resize error: %v", s.roomID, err)
+		}
+	case "grant", "revoke":
+		if c.role != model.Interviewer || msg.Payload == "" {
+			return
+		}
+		s.mu.Lock()
+		if msg.Type == "grant" {
+			s.writers[msg.Payload] = true
+		} else {
+			delete(s.writers, msg.Payload)
+		}
+		rights := s.rightsLocked()
+		s.mu.Unlock()
+		log.Printf("Room %s: %s %s terminal typing for %s", s.roomID, c.username, msg.Type, msg.Payload)
+		s.broadcast(rights)
+	}
+}
+
+func (s *session) canType(c *conn) bool {
+	switch c.role {
+	case model.Interviewer:
+		return true
+	case model.Candidate:
+		s.mu.Lock()
+		granted := s.writers[c.username]
+		s.mu.Unlock()
+		return granted && !socket.IsLocked(s.roomID)
+	}
+	return false
+}
+
+// hasInterviewerLocked reports whether an interviewer has the terminal open.
+// Callers hold s.mu.
+func (s *session) hasInterviewerLocked() bool {
+	for _, c := range s.conns {
+		if c.role == model.Interviewer {
+			return true
+		}
+	}
+	return false
+}
+
+// kick closes the connections of a user kicked from the room and takes away
+// their rights.
+func (s *session) kick(username string) {
+	s.mu.Lock()
+	delete(s.writers, username)
+	for ws, c := range s.conns {
+		if c.username == username {
+			ws.Close()
+		}
+	}
+	rights := s.rightsLocked()
+	s.mu.Unlock()
+	s.broadcast(rights)
+}
+
+// rightsLocked returns the "rights" message for the current rights. Callers
+// hold s.mu.
+func (s *session) rightsLocked() Message {
+	payload := rightsPayload{Writers: []string{}}
+	for username := range s.writers {
+		payload.Writers = append(payload.Writers, username)
+	}
+	data, _ := json.Marshal(payload)
+	return Message{Type: "rights", Payload: string(data)}
+}
+
+// broadcast sends msg to every connection, outside the session lock.
+func (s *session) broadcast(msg Message) {
+	s.mu.Lock()
+	targets := make([]*conn, 0, len(s.conns))
+	for _, c := range s.conns {
+		targets = append(targets, c)
+	}
+	s.mu.Unlock()
+	for _, c := range targets {
+		c.send(msg)
+	}
+}
+
+func (s *session) leave(c *conn) {
+	s.mu.Lock()
+	delete(s.conns, c.ws)
+	s.mu.Unlock()
+	s.closeIfUnused()
+}
+
+// closeIfUnused destroys the terminal once nobody has it open and nobody is
+// left in the room.
+func (s *session) closeIfUnused() {
+	select {
+	case <-s.ready:
+	default:
+		return // still starting; its first connection is on the way
+	}
+	if s.err != nil {
+		return
+	}
+	s.mu.Lock()
+	unused := len(s.conns) == 0
+	s.mu.Unlock()
+	if unused && socket.RoomSize(s.roomID) == 0 {
+		s.close()
+	}
+}
+
+func (s *session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// close destroys the terminal and forgets the session. Later calls do nothing.
+func (s *session) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	sessionsMu.Lock()
+	if sessions[s.roomID] == s {
+		delete(sessions, s.roomID)
+	}
+	sessionsMu.Unlock()
+	if err := s.term.Close(); err != nil {
+		log.Printf("Room %s: failed to close terminal: %v", s.roomID, err)
+	}
+	log.Printf("Room %s: terminal closed", s.roomID)
+}
@@ -1,67 +1,192 @@
 package worker
 
-// import (
-// 	"context"
-// 	"log"
-// 	"sync"
+import (
+	"fmt"
+	"log"
+	"sync"
 
-// 	worker "github.com/contribsys/faktory_worker_go"
-// 	java_job_executor "github.com/namnv2496/go-ide-pair/internal/executor/worker/java_worker"
-// 	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
-// 	python3_job_executor "github.com/namnv2496/go-ide-pair/internal/executor/worker/python3_worker"
-// 	"github.com/namnv2496/go-ide-pair/internal/model"
-// 	"github.com/tranHieuDev23/IdeTwo/models/daos/execution_dao"
-// 	"github.com/tranHieuDev23/IdeTwo/models/daos/source_code_dao"
-// 	"github.com/tranHieuDev23/IdeTwo/utils/configs"
-// )
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/executor/remote"
+	"github.com/namnv2496/go-ide-pair/internal/executor/socket"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker/job_executor"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	"github.com/namnv2496/go-ide-pair/internal/store"
+)
 
-// type FaktoryWorker struct {
-// 	manager worker.Manager
-// }
+var (
+	backend     job_executor.ExecutionBackend
+	executors   = make(map[model.ProgrammingLanguage]*job_executor.LanguageJobExecutor)
+	executorsMu sync.Mutex
+)
 
-// func (worker *FaktoryWorker) Run() {
-// 	worker.manager.Run()
-// }
+// GetExecutor returns the executor for language, or an error if the language
+// is not in the registry or the configured backend is unavailable. When
+// remote workers are enabled, every language is dispatched to them.
+func GetExecutor(language model.ProgrammingLanguage) (job_executor.JobExecutor, error) {
+	lang, ok := configs.GetLanguage(language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %d", language)
+	}
+	if configs.GetInstance().WorkerGRPCAddr != "" {
+		return remote.GetInstance(), nil
+	}
 
-// var sourceDao = source_code_dao.GetInstance()
-// var executionDao = execution_dao.GetInstance()
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	if executor, ok := executors[language]; ok {
+		return executor, nil
+	}
+	b, err := getBackend()
+	if err != nil {
+		return nil, err
+	}
+	executor := job_executor.NewLanguageJobExecutor(b, lang)
+	executors[language] = executor
+	return executor, nil
+}
 
-// func executeJob(ctx context.Context, args ...interface{}) error {
+// getBackend creates the configured backend on first use. Callers hold
+// executorsMu.
+func getBackend() (job_executor.ExecutionBackend, error) {
+	if backend == nil {
+		b, err := job_executor.NewBackend(configs.GetInstance().ExecutionBackend)
+		if err != nil {
+			return nil, err
+		}
+		backend = b
+	}
+	return backend, nil
+}
 
-// 	var executor job_executor.JobExecutor
-// 	switch source.Language {
-// 	case model.Java:
-// 		executor = java_job_executor.GetInstance()
-// 	case model.Python3:
-// 		executor = python3_job_executor.GetInstance()
-// 	default:
-// 		panic("Unsupported language")
-// 	}
+// OpenTerminal starts a shared terminal with files in its working directory.
+// Terminals run on this process's own backend, even when executions are
+// dispatched to remote workers.
+func OpenTerminal(files map[string]string) (job_executor.Terminal, error) {
+	executorsMu.Lock()
+	b, err := getBackend()
+	executorsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	terminals, ok := b.(job_executor.TerminalBackend)
+	if !ok {
+		return nil, fmt.Errorf("the %s backend does not support terminals", configs.GetInstance().ExecutionBackend)
+	}
+	conf := configs.GetInstance()
+	return terminals.OpenTerminal(job_executor.TerminalSpec{
+		Image:       conf.TerminalImage,
+		MemoryLimit: int64(conf.TerminalMemoryLimit) << 20,
+		Sandbox:     configs.TerminalSandbox(),
+		Files:       files,
+	})
+}
 
-// 	output := executor.Execute(*source)
+// WarmUp prepares the backend for every registered language — with Docker,
+// pre-pulls its image and fills its container pool — so first executions
+// aren't slow, and returns the languages that are ready. Failures are only
+// logged: the first execution that needs the language retries. Remote
+// workers warm up on their own.
+func WarmUp() []model.ProgrammingLanguage {
+	var ready []model.ProgrammingLanguage
+	for _, lang := range configs.GetLanguages() {
+		executor, err := GetExecutor(lang.ID)
+		if err != nil {
+			log.Printf("%s: %v", lang.Name, err)
+			continue
+		}
+		warmer, ok := executor.(interface{ Warm() error })
+		if !ok {
+			continue
+		}
+		if err := warmer.Warm(); err != nil {
+			log.Printf("%s: %v", lang.Name, err)
+			continue
+		}
+		ready = append(ready, lang.ID)
+	}
+	return ready
+}
 
-// 	exec.Status = output.Status
-// 	exec.RunTime = output.RunTime
-// 	exec.Output = output.Output
-// 	executionDao.UpdateExecution(*exec)
+// ValidateSource returns an error if source cannot be run.
+func ValidateSource(source model.SourceCode) error {
+	executor, err := GetExecutor(source.Language)
+	if err != nil {
+		return err
+	}
+	if validator, ok := executor.(job_executor.SourceValidator); ok {
+		return validator.Validate(source)
+	}
+	return nil
+}
 
-// 	log.Printf("Job %s finished\n", helper.Jid())
-// 	return nil
-// }
+// ExecuteJob runs job and records its progress and result in the execution store.
+func ExecuteJob(job Job) {
+	var executionStore store.ExecutionStore = store.GetInstance()
+	exec, err := executionStore.GetExecution(job.ID)
+	if err != nil {
+		log.Printf("Job %s: failed to load execution: %v", job.ID, err)
+		return
+	}
+	exec.Status = model.Running
+	if err := executionStore.SaveExecution(exec); err != nil {
+		log.Printf("Job %s: failed to mark running: %v", job.ID, err)
+	}
 
-// var instance *FaktoryWorker
-// var once sync.Once
-// var conf = configs.GetInstance()
-
-// func GetInstance() *FaktoryWorker {
-// 	once.Do(func() {
-// 		manager := worker.NewManager()
-// 		manager.Register("Execute Job", executeJob)
-// 		manager.Concurrency = conf.FaktoryWorkerConcurrency
-// 		manager.ProcessStrictPriorityQueues("critical", "default", "bulk")
-// 		instance = &FaktoryWorker{
-// 			manager: *manager,
-// 		}
-// 	})
-// 	return instance
-// }
+	executor, err := GetExecutor(job.Source.Language)
+	if err != nil {
+		exec.Status = model.RuntimeError
+		exec.Stderr = err.Error()
+	} else {
+		var onOutput job_executor.OutputHandler
+		if job.RoomID != "" {
+			onOutput = func(chunk job_executor.OutputChunk) {
+				socket.BroadcastRunOutput(job.RoomID, job.ID, chunk)
+			}
+		}
+		var output job_executor.JobExecutorOutput
+		if interactive, ok := executor.(job_executor.InteractiveExecutor); ok && job.Source.Interactive && job.RoomID != "" {
+			stdin := socket.OpenRunInput(job.RoomID, job.ID)
+			socket.BroadcastRunStarted(job.RoomID, job.ID, true)
+			output = interactive.ExecuteInteractive(job.Source, stdin, onOutput)
+			socket.CloseRunInput(job.ID)
+		} else {
+			output = executor.Execute(job.Source, onOutput)
+		}
+		exec.Status = model.ExecutionStatus(output.Status)
+		exec.ExitCode = output.ExitCode
+		exec.RunTime = output.RunTime
+		exec.CPUTime = output.CPUTime
+		exec.CompileTime = output.CompileTime
+		exec.Stdout = output.Stdout
+		exec.Stderr = output.Stderr
+		exec.CompileOutput = output.CompileOutput
+		exec.StdoutTruncated = output.StdoutTruncated
+		exec.StderrTruncated = output.StderrTruncated
+		exec.CompileOutputTruncated = output.CompileOutputTruncated
+		exec.PeakMemory = output.PeakMemory
+		exec.TestCases = make([]model.TestCaseResult, 0, len(output.TestCases))
+		for _, tc := range output.TestCases {
+			exec.TestCases = append(exec.TestCases, model.TestCaseResult{
+				Input:           tc.Input,
+				Stdout:          tc.Stdout,
+				Stderr:          tc.Stderr,
+				ExitCode:        tc.ExitCode,
+				RunTime:         tc.RunTime,
+				CPUTime:         tc.CPUTime,
+				Status:          model.ExecutionStatus(tc.Status),
+				StdoutTruncated: tc.StdoutTruncated,
+				StderrTruncated: tc.StderrTruncated,
+				Expected:        tc.Expected,
+				Verdict:         tc.Verdict,
+			})
+		}
+		exec.Verdict = output.Verdict
+	}
+	if err := executionStore.SaveExecution(exec); err != nil {
+		log.Printf("Job %s: failed to save result: %v", job.ID, err)
+	}
+	if job.RoomID != "" {
+		socket.BroadcastRunFinished(job.RoomID, exec)
+	}
+	log.Printf("Job %s finished", job.ID)
+}
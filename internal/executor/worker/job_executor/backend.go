@@ -0,0 +1,266 @@
+package job_executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// ExecutionBackend runs runner scripts in a sandbox. NewBackend picks one of
+// the implementations from the configuration.
+type ExecutionBackend interface {
+	// Prepare readies the backend to run lang — pulling its image, starting
+	// idle sandboxes — so first executions aren't slow. Run retries whatever
+	// failed here.
+	Prepare(lang configs.Language) error
+	// Run runs spec.Cmd over the files of spec.HostDir, streaming its output
+	// to onOutput, and collects the result; the files the run wrote are back
+	// in spec.HostDir afterwards.
+	Run(spec ContainerSpec, onOutput OutputHandler) JobExecutorOutput
+}
+
+// NewBackend returns the backend named by name: "docker", "local" or "fake".
+func NewBackend(name string) (ExecutionBackend, error) {
+	switch name {
+	case "docker":
+		backend, err := NewDockerBackend()
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	case "local":
+		backend, err := NewLocalBackend(configs.GetInstance().LocalCgroupRoot)
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	case "fake":
+		return NewFakeBackend(nil), nil
+	default:
+		return nil, fmt.Errorf("unknown execution backend %q", name)
+	}
+}
+
+const timeoutStatusCode = 124
+
+// killGracePeriod is how long a run may outlast its ContainerSpec.TimeLimit
+// before the backend stops waiting and kills it.
+const killGracePeriod = 5 * time.Second
+
+// ContainerSpec describes a single sandboxed run of a runner script.
+type ContainerSpec struct {
+	// Language is the registry entry the run belongs to; its image and
+	// sandbox settings are used.
+	Language configs.Language
+	Cmd      []string
+	HostDir  string // the sandbox's /workdir
+	// MemoryLimit is in bytes. Every run is limited to one CPU core.
+	MemoryLimit int64
+	// ExitStatuses maps runner-specific exit codes to statuses. Exit code 0 is
+	// always Successful, 124 RuntimeTimeout, and anything else RuntimeError.
+	ExitStatuses map[int]ExecutionStatus
+	// CompileOutputFile, relative to HostDir, is where the runner writes
+	// compiler diagnostics. Empty for interpreted languages without one.
+	CompileOutputFile string
+	// TestCases are the raw inputs whose per-case results the runner writes
+	// under CasesDir; see WriteTestCases.
+	TestCases []string
+	// Judge, when set, judges the test case results; see NewJudge.
+	Judge *Judge
+	// Stdin, when set, is attached to spec.Cmd's stdin.
+	Stdin io.Reader
+	// OutputLimit caps stdout and stderr each, instead of MaxStdoutBytes and
+	// MaxStderrBytes, when set.
+	OutputLimit int
+	// TimeLimit, when set, bounds the whole run. Cmd is expected to stop
+	// itself by then; whatever is still running killGracePeriod later is
+	// killed and the run ends as RuntimeTimeout.
+	TimeLimit time.Duration
+}
+
+// runContext returns the context a run of spec executes under: one that
+// expires killGracePeriod after spec.TimeLimit, or never without a limit.
+func (spec ContainerSpec) runContext() (context.Context, context.CancelFunc) {
+	if spec.TimeLimit <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), spec.TimeLimit+killGracePeriod)
+}
+
+// outputStreams collects the runner's stdout and stderr.
+type outputStreams struct {
+	stdout *chunkWriter
+	stderr *chunkWriter
+}
+
+func newOutputStreams(spec ContainerSpec, onOutput OutputHandler) *outputStreams {
+	stdoutLimit, stderrLimit := MaxStdoutBytes, MaxStderrBytes
+	if spec.OutputLimit > 0 {
+		stdoutLimit, stderrLimit = spec.OutputLimit, spec.OutputLimit
+	}
+	return &outputStreams{
+		stdout: &chunkWriter{stream: "stdout", limit: stdoutLimit, onOutput: onOutput},
+		stderr: &chunkWriter{stream: "stderr", limit: stderrLimit, onOutput: onOutput},
+	}
+}
+
+func (s *outputStreams) flush() {
+	s.stdout.Flush()
+	s.stderr.Flush()
+}
+
+// oomExitCode is how a process killed by the OOM killer (SIGKILL) exits.
+const oomExitCode = 128 + 9
+
+// collectOutput builds the result of a finished run from its exit code, its
+// streams, the files the runner left in spec.HostDir and the sandbox usage.
+func collectOutput(backend ExecutionBackend, spec ContainerSpec, exitCode int, runTime int64, streams *outputStreams, usage sandboxUsage) JobExecutorOutput {
+	status := statusForExitCode(exitCode, spec.ExitStatuses)
+	if usage.oomKilled && exitCode == oomExitCode {
+		status = MemoryLimitExceeded
+	}
+	output := JobExecutorOutput{
+		Status:          status,
+		ExitCode:        exitCode,
+		RunTime:         runTime,
+		Stdout:          streams.stdout.buf.String(),
+		Stderr:          streams.stderr.buf.String(),
+		StdoutTruncated: streams.stdout.truncated,
+		StderrTruncated: streams.stderr.truncated,
+		PeakMemory:      usage.peakMemory,
+	}
+	if spec.CompileOutputFile != "" {
+		output.CompileOutput, output.CompileOutputTruncated = readCapped(filepath.Join(spec.HostDir, spec.CompileOutputFile), MaxCompileOutputBytes)
+		if meta, err := os.ReadFile(filepath.Join(spec.HostDir, CompileMetaFile)); err == nil {
+			fmt.Sscan(string(meta), &output.CompileTime)
+		}
+	}
+	if status == CompileError {
+		// Nothing ran; the time went into compiling.
+		output.RunTime = 0
+	}
+	if status != CompileError && len(spec.TestCases) > 0 {
+		output.TestCases = readTestCaseResults(spec.HostDir, spec.TestCases)
+		output.RunTime = 0
+		for _, tc := range output.TestCases {
+			output.RunTime += tc.RunTime
+			output.CPUTime += tc.CPUTime
+		}
+		if usage.oomKilled {
+			// The killed process exits like any SIGKILL; the container's OOM
+			// flag tells which it was.
+			for i := range output.TestCases {
+				if output.TestCases[i].ExitCode == oomExitCode {
+					output.TestCases[i].Status = MemoryLimitExceeded
+				}
+			}
+		}
+		// The runner itself exits 0 after running every case, so the
+		// overall result comes from the cases unless the runner failed.
+		if status == Successful {
+			output.Status, output.ExitCode = summarizeTestCases(output.TestCases)
+		}
+		if spec.Judge != nil {
+			output.Verdict = spec.Judge.judgeTestCases(backend, spec, output.TestCases)
+		}
+	}
+	// As for a single test case, a flood of output is the real problem
+	// however the program ended. This covers runs without test cases too.
+	if (output.StdoutTruncated || output.StderrTruncated) && output.Status != CompileError && output.Status != CompileTimeout {
+		output.Status = OutputLimitExceeded
+	}
+	return output
+}
+
+// statusForExitCode maps a process exit code to a status. Exit code 0 is
+// Successful and 124 RuntimeTimeout unless overridden by custom.
+func statusForExitCode(code int, custom map[int]ExecutionStatus) ExecutionStatus {
+	if status, ok := custom[code]; ok {
+		return status
+	}
+	switch code {
+	case 0:
+		return Successful
+	case timeoutStatusCode:
+		return RuntimeTimeout
+	default:
+		return RuntimeError
+	}
+}
+
+// readCapped reads at most limit bytes of path. A missing file reads as empty.
+func readCapped(path string, limit int) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, int64(limit)+1))
+	if err != nil {
+		log.Printf("Warning: failed to read %s: %v", path, err)
+	}
+	if len(data) > limit {
+		return string(data[:limit]), true
+	}
+	return string(data), false
+}
+
+// chunkWriter collects up to limit bytes of one output stream and forwards
+// them to onOutput as they are written; the rest is discarded. A UTF-8
+// sequence split across writes is held back until it is complete so chunks
+// are always valid strings.
+type chunkWriter struct {
+	stream    string
+	limit     int
+	onOutput  OutputHandler
+	buf       bytes.Buffer
+	truncated bool
+	pending   []byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := w.limit - w.buf.Len(); len(p) > room {
+		p = p[:max(room, 0)]
+		w.truncated = true
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+	w.buf.Write(p)
+	if w.onOutput == nil {
+		return n, nil
+	}
+	data := append(w.pending, p...)
+	cut := len(data)
+	// Step back over at most one incomplete trailing rune.
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	w.pending = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		w.onOutput(OutputChunk{Stream: w.stream, Data: string(data[:cut])})
+	}
+	return n, nil
+}
+
+// Flush delivers any bytes still held back.
+func (w *chunkWriter) Flush() {
+	if w.onOutput != nil && len(w.pending) > 0 {
+		w.onOutput(OutputChunk{Stream: w.stream, Data: string(w.pending)})
+	}
+	w.pending = nil
+}
@@ -0,0 +1,40 @@
+package job_executor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// CompileCommand returns lang's compile command for source, with {standard}
+// and {optimization} replaced by the chosen entries of lang's Standards and
+// Optimizations — the first of each when source leaves them empty.
+func CompileCommand(lang configs.Language, source model.SourceCode) (string, error) {
+	std, err := compilerOption("standard", source.Standard, lang.Standards)
+	if err != nil {
+		return "", err
+	}
+	opt, err := compilerOption("optimization", source.Optimization, lang.Optimizations)
+	if err != nil {
+		return "", err
+	}
+	return strings.NewReplacer("{standard}", std, "{optimization}", opt).Replace(lang.Compile), nil
+}
+
+// compilerOption validates value against accepted, defaulting to its first
+// entry. A language that accepts no values ignores the option altogether.
+func compilerOption(name, value string, accepted []string) (string, error) {
+	if len(accepted) == 0 {
+		return "", nil
+	}
+	if value == "" {
+		return accepted[0], nil
+	}
+	if !slices.Contains(accepted, value) {
+		return "", fmt.Errorf("unsupported %s %q, expected one of %v", name, value, accepted)
+	}
+	return value, nil
+}
@@ -0,0 +1,114 @@
+package job_executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// DockerBackend runs each language in containers of its image, taken from a
+// per-language pool when pooling is enabled.
+type DockerBackend struct {
+	cli *client.Client
+
+	mu        sync.Mutex
+	languages map[model.ProgrammingLanguage]*dockerLanguage
+}
+
+// dockerLanguage is the backend's state for one language.
+type dockerLanguage struct {
+	mu     sync.Mutex
+	pulled bool
+	pool   *ContainerPool
+}
+
+// NewDockerBackend connects to the Docker daemon named by the environment and
+// removes the pooled containers a previous run of the server left behind.
+func NewDockerBackend() (*DockerBackend, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	RemovePoolContainers(cli)
+	return &DockerBackend{cli: cli, languages: make(map[model.ProgrammingLanguage]*dockerLanguage)}, nil
+}
+
+// resourcesFor limits a container to memoryLimit bytes and one CPU core.
+func resourcesFor(memoryLimit int64) container.Resources {
+	return container.Resources{
+		Memory:   memoryLimit,
+		CPUQuota: 100000, // 1 CPU core
+	}
+}
+
+// Prepare pulls lang's image and starts its pool's idle containers.
+func (b *DockerBackend) Prepare(lang configs.Language) error {
+	state, err := b.ready(lang)
+	if err != nil {
+		return err
+	}
+	if state.pool != nil {
+		return state.pool.Fill()
+	}
+	return nil
+}
+
+// Run runs spec in a pooled container of its language, or in a fresh one
+// when pooling is disabled.
+func (b *DockerBackend) Run(spec ContainerSpec, onOutput OutputHandler) JobExecutorOutput {
+	state, err := b.ready(spec.Language)
+	if err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: err.Error()}
+	}
+	if state.pool != nil {
+		return state.pool.Run(spec, onOutput)
+	}
+	return b.runCold(spec, onOutput)
+}
+
+// ready returns lang's state once its image is present. A failed pull is
+// retried by the next call.
+func (b *DockerBackend) ready(lang configs.Language) (*dockerLanguage, error) {
+	b.mu.Lock()
+	state, ok := b.languages[lang.ID]
+	if !ok {
+		state = &dockerLanguage{}
+		if conf := configs.GetInstance(); conf.PoolSize > 0 {
+			state.pool = NewContainerPool(b, lang.Image, resourcesFor(int64(lang.MemoryLimit)<<20), lang.Sandbox, conf.PoolSize, max(conf.PoolMaxReuse, 1))
+		}
+		b.languages[lang.ID] = state
+	}
+	b.mu.Unlock()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if !state.pulled {
+		if err := PullImage(b.cli, lang.Image); err != nil {
+			return nil, err
+		}
+		state.pulled = true
+	}
+	return state, nil
+}
+
+// runCold runs spec in a fresh sandbox container and removes the container
+// afterwards.
+func (b *DockerBackend) runCold(spec ContainerSpec, onOutput OutputHandler) JobExecutorOutput {
+	id, err := createSandbox(b.cli, spec.Language.Image, resourcesFor(spec.MemoryLimit), spec.Language.Sandbox, nil)
+	if err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to create container: %v", err)}
+	}
+	defer func() {
+		if err := b.cli.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true}); err != nil {
+			log.Printf("Warning: failed to remove container %s: %v", id, err)
+		}
+	}()
+	output, _ := b.runInSandbox(id, spec, onOutput)
+	return output
+}
@@ -9,4 +9,7 @@ const (
 	RuntimeError
 	RuntimeTimeout
 	Successful
+	_ // model.Running
+	MemoryLimitExceeded
+	OutputLimitExceeded
 )
@@ -0,0 +1,122 @@
+package job_executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// FakeBackend runs nothing: it records every run and answers it with files
+// in spec.HostDir, as a runner would have left them, so executors and the
+// worker can be exercised without a sandbox. The answers are read back and
+// judged like those of a real run, and an interactive run echoes its stdin
+// like cat.
+type FakeBackend struct {
+	respond func(spec ContainerSpec) FakeRun
+
+	mu   sync.Mutex
+	runs []ContainerSpec
+}
+
+// FakeRun is how a FakeBackend answers a run.
+type FakeRun struct {
+	// ExitCode is the runner's, e.g. 124 for a run that used up its total
+	// time limit or the language's CompileErrorExitCode.
+	ExitCode int
+	// CompileOutput goes to spec.CompileOutputFile when the spec has one.
+	CompileOutput string
+	// TestCases are the results of the cases that ran, in order; the rest
+	// never ran. Only Stdout, Stderr, ExitCode, RunTime and CPUTime are
+	// used: the statuses follow from them as for a real run.
+	TestCases []TestCaseResult
+}
+
+// NewFakeBackend returns a backend that answers each run with respond. A nil
+// respond makes every test case succeed, printing its input back.
+func NewFakeBackend(respond func(spec ContainerSpec) FakeRun) *FakeBackend {
+	if respond == nil {
+		respond = echoTestCases
+	}
+	return &FakeBackend{respond: respond}
+}
+
+func echoTestCases(spec ContainerSpec) FakeRun {
+	results := make([]TestCaseResult, len(spec.TestCases))
+	for i, input := range spec.TestCases {
+		results[i] = TestCaseResult{Stdout: input}
+	}
+	return FakeRun{TestCases: results}
+}
+
+func (b *FakeBackend) Prepare(lang configs.Language) error {
+	return nil
+}
+
+func (b *FakeBackend) Run(spec ContainerSpec, onOutput OutputHandler) JobExecutorOutput {
+	b.mu.Lock()
+	b.runs = append(b.runs, spec)
+	b.mu.Unlock()
+
+	streams := newOutputStreams(spec, onOutput)
+	if spec.Stdin != nil {
+		_, _ = io.Copy(streams.stdout, spec.Stdin)
+	}
+	run := b.respond(spec)
+	if err := writeFakeRun(spec, run); err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to write the fake run: %v", err)}
+	}
+	for _, tc := range run.TestCases {
+		_, _ = streams.stdout.Write([]byte(tc.Stdout))
+		_, _ = streams.stderr.Write([]byte(tc.Stderr))
+	}
+	streams.flush()
+	return collectOutput(b, spec, run.ExitCode, 0, streams, sandboxUsage{})
+}
+
+// writeFakeRun writes the compile output and per-case files run stands for.
+func writeFakeRun(spec ContainerSpec, run FakeRun) error {
+	if spec.CompileOutputFile != "" {
+		if err := os.WriteFile(filepath.Join(spec.HostDir, spec.CompileOutputFile), []byte(run.CompileOutput), 0644); err != nil {
+			return err
+		}
+	}
+	for i, tc := range run.TestCases {
+		prefix := filepath.Join(spec.HostDir, CasesDir, fmt.Sprintf("%d", i+1))
+		files := map[string]string{
+			".out":  tc.Stdout,
+			".err":  tc.Stderr,
+			".meta": fmt.Sprintf("%d %d %d", tc.ExitCode, tc.RunTime, tc.CPUTime),
+		}
+		for ext, content := range files {
+			if err := os.WriteFile(prefix+ext, []byte(content), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Runs returns the specs of every run so far, oldest first.
+func (b *FakeBackend) Runs() []ContainerSpec {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]ContainerSpec(nil), b.runs...)
+}
+
+// OpenTerminal opens a terminal that echoes what is typed, like a TTY in
+// cooked mode with nothing reading it.
+func (b *FakeBackend) OpenTerminal(spec TerminalSpec) (Terminal, error) {
+	return &fakeTerminal{InputBuffer: NewInputBuffer()}, nil
+}
+
+type fakeTerminal struct {
+	*InputBuffer
+}
+
+func (t *fakeTerminal) Resize(rows, cols uint) error {
+	return nil
+}
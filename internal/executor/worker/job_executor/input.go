@@ -0,0 +1,65 @@
+package job_executor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// MaxPendingInputBytes caps the input an InputBuffer holds before the
+// program reads it; anything beyond is dropped.
+const MaxPendingInputBytes = 64 << 10
+
+// InputBuffer is the stdin of an interactive run. Writes never block, so a
+// program that doesn't read its input cannot hold up whoever is typing; Read
+// blocks until input arrives or the buffer is closed.
+type InputBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    bytes.Buffer
+	closed bool
+}
+
+func NewInputBuffer() *InputBuffer {
+	in := &InputBuffer{}
+	in.cond = sync.NewCond(&in.mu)
+	return in
+}
+
+// Write queues p for the program. It reports io.ErrClosedPipe once the buffer
+// is closed and io.ErrShortWrite when p did not fit.
+func (in *InputBuffer) Write(p []byte) (int, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n := min(len(p), MaxPendingInputBytes-in.buf.Len())
+	in.buf.Write(p[:n])
+	in.cond.Broadcast()
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+func (in *InputBuffer) Read(p []byte) (int, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for in.buf.Len() == 0 && !in.closed {
+		in.cond.Wait()
+	}
+	if in.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return in.buf.Read(p)
+}
+
+// Close ends the input: the program reads what is left, then EOF.
+func (in *InputBuffer) Close() error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.closed = true
+	in.cond.Broadcast()
+	return nil
+}
@@ -1,14 +1,70 @@
 package job_executor
 
-import "github.com/namnv2496/go-ide-pair/internal/model"
+import (
+	"io"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// Output caps, applied independently to each stream. Anything beyond is
+// dropped and the matching Truncated flag is set.
+const (
+	MaxStdoutBytes        = 8192
+	MaxStderrBytes        = 8192
+	MaxCompileOutputBytes = 8192
+	// MaxInteractiveOutputBytes replaces MaxStdoutBytes and MaxStderrBytes for
+	// interactive runs, whose output is a whole session.
+	MaxInteractiveOutputBytes = 64 << 10
+)
 
 type JobExecutorOutput struct {
 	Status   ExecutionStatus
 	ExitCode int
-	RunTime  int64
-	Output   string
+	// RunTime and CPUTime are the wall and CPU (user+sys) time of all test
+	// cases together, and CompileTime the wall time of the compile step, in
+	// ms. All are measured inside the sandbox.
+	RunTime     int64
+	CPUTime     int64
+	CompileTime int64
+	// Stdout and Stderr are the program's streams; failures of the sandbox
+	// itself are also reported in Stderr.
+	Stdout                 string
+	Stderr                 string
+	CompileOutput          string
+	StdoutTruncated        bool
+	StderrTruncated        bool
+	CompileOutputTruncated bool
+	// PeakMemory is the peak memory usage of the sandbox's cgroup in KB, 0
+	// when it could not be read or, in a reused pooled container, did not
+	// exceed that of an earlier run.
+	PeakMemory int64
+	TestCases  []TestCaseResult
+	Verdict    model.Verdict
 }
 
+// OutputChunk is a piece of program output delivered while the program runs.
+type OutputChunk struct {
+	Stream string `json:"stream"` // "stdout" or "stderr"
+	Data   string `json:"data"`
+}
+
+// OutputHandler receives output as it is produced. It may be nil.
+type OutputHandler func(chunk OutputChunk)
+
 type JobExecutor interface {
-	Execute(source model.SourceCode) JobExecutorOutput
+	Execute(source model.SourceCode, onOutput OutputHandler) JobExecutorOutput
+}
+
+// InteractiveExecutor is implemented by executors that can run a program
+// with stdin attached: it reads stdin as the program does, until stdin ends
+// or the program exits. The caller closes stdin once the run is over.
+type InteractiveExecutor interface {
+	ExecuteInteractive(source model.SourceCode, stdin io.Reader, onOutput OutputHandler) JobExecutorOutput
+}
+
+// SourceValidator is implemented by executors that only accept some sources,
+// e.g. a known set of compiler flags. Validate is called before the source
+// is queued so bad requests are rejected up front.
+type SourceValidator interface {
+	Validate(source model.SourceCode) error
 }
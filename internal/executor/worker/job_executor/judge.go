@@ -0,0 +1,189 @@
+package job_executor
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+const defaultTolerance = 1e-6
+
+// Judge compares the output of each test case with its expected output.
+type Judge struct {
+	Expected   []*string
+	Comparator model.Comparator
+	Tolerance  float64
+	Checker    string
+}
+
+// NewJudge returns the judge for source, or nil when no test case has an
+// expected output.
+func NewJudge(source model.SourceCode) *Judge {
+	if !slices.ContainsFunc(source.Expected, func(e *string) bool { return e != nil }) {
+		return nil
+	}
+	judge := &Judge{
+		Expected:   source.Expected,
+		Comparator: source.Comparator,
+		Tolerance:  source.Tolerance,
+		Checker:    source.Checker,
+	}
+	if judge.Comparator == "" {
+		judge.Comparator = model.ExactComparator
+	}
+	if judge.Tolerance <= 0 {
+		judge.Tolerance = defaultTolerance
+	}
+	return judge
+}
+
+// judgeTestCases sets Expected and Verdict on results and returns the overall
+// verdict. Only cases that ran successfully are compared; the custom checker
+// runs in a second sandbox of spec's language once the program has exited,
+// so the program never sees the expected output.
+func (j *Judge) judgeTestCases(backend ExecutionBackend, spec ContainerSpec, results []TestCaseResult) model.Verdict {
+	var toCheck []int
+	for i := range results {
+		if i >= len(j.Expected) || j.Expected[i] == nil {
+			continue
+		}
+		results[i].Expected = j.Expected[i]
+		if results[i].Status != Successful {
+			continue
+		}
+		if j.Comparator == model.CustomComparator {
+			toCheck = append(toCheck, i)
+			continue
+		}
+		results[i].Verdict = verdictOf(j.compare(results[i].Stdout, *j.Expected[i]))
+	}
+	if len(toCheck) > 0 {
+		if err := j.runChecker(backend, spec, results, toCheck); err != nil {
+			log.Printf("Warning: checker failed: %v", err)
+		}
+	}
+
+	overall := model.NotJudged
+	for _, r := range results {
+		if r.Expected == nil {
+			continue
+		}
+		if r.Verdict != model.Accepted {
+			return model.WrongAnswer
+		}
+		overall = model.Accepted
+	}
+	return overall
+}
+
+// compare applies one of the built-in comparators.
+func (j *Judge) compare(actual, expected string) bool {
+	switch j.Comparator {
+	case model.WhitespaceComparator:
+		return slices.Equal(strings.Fields(actual), strings.Fields(expected))
+	case model.UnorderedLinesComparator:
+		a, e := nonEmptyLines(actual), nonEmptyLines(expected)
+		slices.Sort(a)
+		slices.Sort(e)
+		return slices.Equal(a, e)
+	case model.FloatComparator:
+		a, e := strings.Fields(actual), strings.Fields(expected)
+		if len(a) != len(e) {
+			return false
+		}
+		for i := range a {
+			if !tokensMatch(a[i], e[i], j.Tolerance) {
+				return false
+			}
+		}
+		return true
+	default:
+		return strings.TrimRight(actual, "\r\n") == strings.TrimRight(expected, "\r\n")
+	}
+}
+
+// tokensMatch compares two tokens as numbers when both parse as one, and as
+// strings otherwise.
+func tokensMatch(actual, expected string, tolerance float64) bool {
+	a, errA := strconv.ParseFloat(actual, 64)
+	e, errE := strconv.ParseFloat(expected, 64)
+	if errA != nil || errE != nil {
+		return actual == expected
+	}
+	diff := math.Abs(a - e)
+	return diff <= tolerance || diff <= tolerance*math.Abs(e)
+}
+
+// nonEmptyLines splits s into lines without trailing whitespace, dropping
+// blank ones.
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// checkerCaseTimeout is the timeout checkerScript gives checker.sh for each
+// test case.
+const checkerCaseTimeout = 10 * time.Second
+
+// checkerScript runs checker.sh for every cases/<i>.expected and writes its
+// exit code to cases/<i>.check.
+const checkerScript = `for expected in cases/*.expected; do
+    case_file="${expected%.expected}"
+    timeout 10s sh checker.sh "$case_file.in" "$expected" "$case_file.out" > /dev/null 2>&1
+    echo $? > "$case_file.check"
+done
+`
+
+// runChecker writes the expected output of the cases in toCheck next to their
+// results and runs the checker over them.
+func (j *Judge) runChecker(backend ExecutionBackend, spec ContainerSpec, results []TestCaseResult, toCheck []int) error {
+	for _, i := range toCheck {
+		path := filepath.Join(spec.HostDir, CasesDir, fmt.Sprintf("%d.expected", i+1))
+		if err := os.WriteFile(path, []byte(*results[i].Expected), fs.FileMode(0644)); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(spec.HostDir, "checker.sh"), []byte(j.Checker), fs.FileMode(0755)); err != nil {
+		return err
+	}
+
+	output := backend.Run(ContainerSpec{
+		Language:    spec.Language,
+		Cmd:         []string{"sh", "-c", checkerScript},
+		HostDir:     spec.HostDir,
+		MemoryLimit: spec.MemoryLimit,
+		TimeLimit:   time.Duration(len(toCheck)) * checkerCaseTimeout,
+	}, nil)
+	if output.Status != Successful {
+		return fmt.Errorf("checker exited with %d: %s", output.ExitCode, output.Stderr)
+	}
+	for _, i := range toCheck {
+		data, err := os.ReadFile(filepath.Join(spec.HostDir, CasesDir, fmt.Sprintf("%d.check", i+1)))
+		if err != nil {
+			return err
+		}
+		results[i].Verdict = verdictOf(strings.TrimSpace(string(data)) == "0")
+	}
+	return nil
+}
+
+func verdictOf(accepted bool) model.Verdict {
+	if accepted {
+		return model.Accepted
+	}
+	return model.WrongAnswer
+}
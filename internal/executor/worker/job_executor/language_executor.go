@@ -0,0 +1,148 @@
+package job_executor
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// LanguageJobExecutor runs any language of the registry: it writes the
+// source to lang.SourceFile, or a project's files to their paths, and runs a
+// ShellRunner built from lang's commands on an ExecutionBackend.
+type LanguageJobExecutor struct {
+	backend ExecutionBackend
+	lang    configs.Language
+}
+
+func NewLanguageJobExecutor(backend ExecutionBackend, lang configs.Language) *LanguageJobExecutor {
+	return &LanguageJobExecutor{backend: backend, lang: lang}
+}
+
+// Language returns the registry entry the executor runs.
+func (executor *LanguageJobExecutor) Language() configs.Language {
+	return executor.lang
+}
+
+// Warm prepares the backend for the language, e.g. pulls its image.
+func (executor *LanguageJobExecutor) Warm() error {
+	return executor.backend.Prepare(executor.lang)
+}
+
+// Validate rejects unsupported standards and optimization levels, and
+// projects without their entrypoint.
+func (executor *LanguageJobExecutor) Validate(source model.SourceCode) error {
+	if _, err := CompileCommand(executor.lang, source); err != nil {
+		return err
+	}
+	return validateProject(executor.lang, source)
+}
+
+func (executor *LanguageJobExecutor) Execute(source model.SourceCode, onOutput OutputHandler) JobExecutorOutput {
+	return executor.execute(source, nil, onOutput)
+}
+
+// ExecuteInteractive runs the program once with stdin attached, limited to
+// the configured InteractiveTimeLimit instead of the language's limits.
+func (executor *LanguageJobExecutor) ExecuteInteractive(source model.SourceCode, stdin io.Reader, onOutput OutputHandler) JobExecutorOutput {
+	return executor.execute(source, stdin, onOutput)
+}
+
+// execute runs source over its test cases, or interactively when stdin is
+// set.
+func (executor *LanguageJobExecutor) execute(source model.SourceCode, stdin io.Reader, onOutput OutputHandler) JobExecutorOutput {
+	compile, err := CompileCommand(executor.lang, source)
+	if err != nil {
+		return JobExecutorOutput{Status: CompileError, CompileOutput: err.Error()}
+	}
+	if err := validateProject(executor.lang, source); err != nil {
+		return JobExecutorOutput{Status: CompileError, CompileOutput: err.Error()}
+	}
+
+	dir, err := os.MkdirTemp("", "workdir")
+	if err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to create temp dir: %v", err)}
+	}
+	defer os.RemoveAll(dir)
+
+	if err := executor.writeSourceFile(dir, source, compile, stdin != nil); err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to write source file: %v", err)}
+	}
+
+	return executor.runExecutable(dir, source, compile, stdin, onOutput)
+}
+
+// writeSourceFile writes the project, one cases/<i>.in per test case, and
+// runner.sh. An interactive run has no test cases.
+func (executor *LanguageJobExecutor) writeSourceFile(dir string, source model.SourceCode, compile string, interactive bool) error {
+	lang := executor.lang
+	files, entrypoint := projectFiles(lang, source)
+	if err := writeProject(dir, files); err != nil {
+		return err
+	}
+	compile = ProjectCommand(compile, entrypoint, files)
+	if interactive {
+		run := ProjectCommand(lang.InteractiveRun, entrypoint, files)
+		script := InteractiveRunner(compile, run, lang.CompileErrorExitCode)
+		return os.WriteFile(filepath.Join(dir, "runner.sh"), []byte(script), fs.FileMode(0755))
+	}
+	if err := WriteTestCases(dir, TestCaseInputs(source.Input, lang.Input)); err != nil {
+		return err
+	}
+	timeLimit := lang.TimeLimit
+	if source.TimeLimit > 0 {
+		timeLimit = source.TimeLimit
+	}
+	run := ProjectCommand(lang.Run, entrypoint, files)
+	script := ShellRunner(compile, run, time.Duration(timeLimit)*time.Millisecond, lang.CompileErrorExitCode)
+	return os.WriteFile(filepath.Join(dir, "runner.sh"), []byte(script), fs.FileMode(0755))
+}
+
+// runExecutable runs runner.sh on the backend, limited to
+// lang.TotalTimeLimit overall, or InteractiveTimeLimit with stdin attached.
+func (executor *LanguageJobExecutor) runExecutable(dir string, source model.SourceCode, compile string, stdin io.Reader, onOutput OutputHandler) JobExecutorOutput {
+	lang := executor.lang
+	memoryLimit := lang.MemoryLimit
+	if source.MemoryLimit > 0 {
+		memoryLimit = source.MemoryLimit
+	}
+	totalTimeLimit := lang.TotalTimeLimit
+	if stdin != nil {
+		totalTimeLimit = configs.GetInstance().InteractiveTimeLimit
+	}
+	timeLimit := time.Duration(totalTimeLimit) * time.Millisecond
+	spec := ContainerSpec{
+		Language: lang,
+		Cmd: []string{
+			"sh", "-c",
+			fmt.Sprintf("timeout --foreground %gs sh runner.sh", timeLimit.Seconds()),
+		},
+		HostDir:     dir,
+		MemoryLimit: int64(memoryLimit) << 20,
+		// timeout --foreground leaves the runner's children running, so the
+		// backend enforces the limit too.
+		TimeLimit: timeLimit,
+	}
+	if stdin != nil {
+		spec.Stdin = stdin
+		spec.OutputLimit = MaxInteractiveOutputBytes
+	} else {
+		spec.TestCases = SplitTestCases(source.Input)
+		spec.Judge = NewJudge(source)
+	}
+	if compile != "" {
+		spec.ExitStatuses = map[int]ExecutionStatus{lang.CompileErrorExitCode: CompileError}
+		spec.CompileOutputFile = "compile_output.txt"
+	}
+	output := executor.backend.Run(spec, onOutput)
+	if stdin != nil && output.Status != CompileError {
+		// Without test cases the run time is the runner's, compile included.
+		output.RunTime = max(output.RunTime-output.CompileTime, 0)
+	}
+	return output
+}
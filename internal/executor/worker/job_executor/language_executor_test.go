@@ -0,0 +1,249 @@
+package job_executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// fakeExecutor returns an executor for the registry's id on a FakeBackend
+// answering with respond.
+func fakeExecutor(t *testing.T, id model.ProgrammingLanguage, respond func(spec ContainerSpec) FakeRun) (*LanguageJobExecutor, *FakeBackend) {
+	t.Helper()
+	lang, ok := configs.GetLanguage(id)
+	if !ok {
+		t.Fatalf("language %d is not in the registry", id)
+	}
+	backend := NewFakeBackend(respond)
+	return NewLanguageJobExecutor(backend, lang), backend
+}
+
+// answer makes every test case exit with code and print stdout.
+func answer(code int, stdout ...string) func(spec ContainerSpec) FakeRun {
+	return func(spec ContainerSpec) FakeRun {
+		var run FakeRun
+		for i := range spec.TestCases {
+			run.TestCases = append(run.TestCases, TestCaseResult{Stdout: stdout[i%len(stdout)], ExitCode: code})
+		}
+		return run
+	}
+}
+
+func TestExecuteEchoes(t *testing.T) {
+	executor, backend := fakeExecutor(t, model.Python3, nil)
+	output := executor.Execute(model.SourceCode{Language: model.Python3, Content: "print(x)", Input: "x = 1\nx = 2"}, nil)
+	if output.Status != Successful || output.Stdout != "x = 1x = 2" || len(output.TestCases) != 2 {
+		t.Fatalf("got status %d, stdout %q and %d test cases", output.Status, output.Stdout, len(output.TestCases))
+	}
+	if runs := backend.Runs(); len(runs) != 1 {
+		t.Errorf("got %d runs, want 1", len(runs))
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	executor, _ := fakeExecutor(t, model.Python3, func(spec ContainerSpec) FakeRun {
+		return FakeRun{ExitCode: spec.Language.CompileErrorExitCode, CompileOutput: "SyntaxError: invalid syntax"}
+	})
+	output := executor.Execute(model.SourceCode{Language: model.Python3, Content: "print("}, nil)
+	if output.Status != CompileError || output.CompileOutput != "SyntaxError: invalid syntax" {
+		t.Errorf("got status %d, compile output %q", output.Status, output.CompileOutput)
+	}
+	if output.TestCases != nil || output.RunTime != 0 {
+		t.Errorf("a program that did not compile reported %d test cases and %d ms", len(output.TestCases), output.RunTime)
+	}
+}
+
+func TestUnsupportedStandardIsACompileError(t *testing.T) {
+	executor, backend := fakeExecutor(t, model.Cpp, nil)
+	output := executor.Execute(model.SourceCode{Language: model.Cpp, Content: "int main() {}", Standard: "c++98"}, nil)
+	if output.Status != CompileError || output.CompileOutput == "" {
+		t.Errorf("got status %d, compile output %q", output.Status, output.CompileOutput)
+	}
+	if runs := backend.Runs(); len(runs) != 0 {
+		t.Errorf("the backend ran %d times", len(runs))
+	}
+}
+
+func TestTimeLimit(t *testing.T) {
+	var script string
+	executor, backend := fakeExecutor(t, model.Python3, func(spec ContainerSpec) FakeRun {
+		data, err := os.ReadFile(filepath.Join(spec.HostDir, "runner.sh"))
+		if err != nil {
+			t.Error(err)
+		}
+		script = string(data)
+		return FakeRun{TestCases: []TestCaseResult{{Stdout: "1\n"}, {ExitCode: timeoutStatusCode, RunTime: 1500}}}
+	})
+	output := executor.Execute(model.SourceCode{Language: model.Python3, Content: "print(x)", Input: "x = 1\nx = 2", TimeLimit: 1500}, nil)
+	if !strings.Contains(script, "timeout 1.5s") {
+		t.Errorf("runner.sh does not limit each case to 1.5s:\n%s", script)
+	}
+	lang := executor.Language()
+	if spec := backend.Runs()[0]; spec.TimeLimit.Milliseconds() != int64(lang.TotalTimeLimit) {
+		t.Errorf("the run is limited to %v, want %d ms", spec.TimeLimit, lang.TotalTimeLimit)
+	}
+	if output.Status != RuntimeTimeout || output.ExitCode != timeoutStatusCode {
+		t.Errorf("got status %d, exit code %d", output.Status, output.ExitCode)
+	}
+	if got := output.TestCases; got[0].Status != Successful || got[1].Status != RuntimeTimeout {
+		t.Errorf("got case statuses %d and %d", got[0].Status, got[1].Status)
+	}
+}
+
+func TestTotalTimeLimit(t *testing.T) {
+	// The runner was stopped during the second of three cases.
+	executor, _ := fakeExecutor(t, model.Python3, func(spec ContainerSpec) FakeRun {
+		return FakeRun{ExitCode: timeoutStatusCode, TestCases: []TestCaseResult{{Stdout: "1\n"}}}
+	})
+	output := executor.Execute(model.SourceCode{Language: model.Python3, Content: "print(x)", Input: "x = 1\nx = 2\nx = 3"}, nil)
+	if output.Status != RuntimeTimeout {
+		t.Errorf("got status %d, want RuntimeTimeout", output.Status)
+	}
+	for i, want := range []ExecutionStatus{Successful, NotExecuted, NotExecuted} {
+		if got := output.TestCases[i].Status; got != want {
+			t.Errorf("case %d has status %d, want %d", i+1, got, want)
+		}
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	flood := strings.Repeat("y\n", MaxStdoutBytes)
+	executor, _ := fakeExecutor(t, model.Python3, answer(0, flood))
+	output := executor.Execute(model.SourceCode{Language: model.Python3, Content: "while True: print('y')"}, nil)
+	if output.Status != OutputLimitExceeded {
+		t.Errorf("got status %d, want OutputLimitExceeded", output.Status)
+	}
+	if !output.StdoutTruncated || len(output.Stdout) != MaxStdoutBytes {
+		t.Errorf("got %d bytes of stdout, truncated %v", len(output.Stdout), output.StdoutTruncated)
+	}
+	if tc := output.TestCases[0]; !tc.StdoutTruncated || len(tc.Stdout) != MaxStdoutBytes {
+		t.Errorf("the test case kept %d bytes of stdout, truncated %v", len(tc.Stdout), tc.StdoutTruncated)
+	}
+}
+
+func TestOutputLimitWithoutTestCases(t *testing.T) {
+	backend := NewFakeBackend(nil)
+	flood := strings.NewReader(strings.Repeat("y\n", MaxStdoutBytes))
+	output := backend.Run(ContainerSpec{HostDir: t.TempDir(), Stdin: flood}, nil)
+	if output.Status != OutputLimitExceeded || !output.StdoutTruncated {
+		t.Errorf("got status %d, truncated %v; want OutputLimitExceeded", output.Status, output.StdoutTruncated)
+	}
+}
+
+func TestInteractiveOutputLimit(t *testing.T) {
+	executor, _ := fakeExecutor(t, model.Python3, nil)
+	stdin := strings.NewReader(strings.Repeat("y", MaxInteractiveOutputBytes+1))
+	output := executor.ExecuteInteractive(model.SourceCode{Language: model.Python3, Content: "print(input())"}, stdin, nil)
+	if !output.StdoutTruncated || len(output.Stdout) != MaxInteractiveOutputBytes {
+		t.Errorf("got %d bytes of stdout, truncated %v", len(output.Stdout), output.StdoutTruncated)
+	}
+	if output.Status != OutputLimitExceeded {
+		t.Errorf("got status %d, want OutputLimitExceeded", output.Status)
+	}
+}
+
+func TestVerdicts(t *testing.T) {
+	expected := func(outputs ...string) []*string {
+		pointers := make([]*string, len(outputs))
+		for i := range outputs {
+			pointers[i] = &outputs[i]
+		}
+		return pointers
+	}
+	tests := []struct {
+		name       string
+		stdout     string
+		code       int
+		expected   []*string
+		comparator model.Comparator
+		want       model.Verdict
+	}{
+		{"exact", "3\n", 0, expected("3"), "", model.Accepted},
+		{"exact mismatch", "3 \n", 0, expected("3"), model.ExactComparator, model.WrongAnswer},
+		{"whitespace", "1  2\n3\n", 0, expected("1 2 3"), model.WhitespaceComparator, model.Accepted},
+		{"unordered lines", "b\na\n", 0, expected("a\nb"), model.UnorderedLinesComparator, model.Accepted},
+		{"unordered lines mismatch", "b\nb\n", 0, expected("a\nb"), model.UnorderedLinesComparator, model.WrongAnswer},
+		{"float", "0.3333333\n", 0, expected("0.33333333"), model.FloatComparator, model.Accepted},
+		{"float mismatch", "0.33\n", 0, expected("0.33333333"), model.FloatComparator, model.WrongAnswer},
+		{"runtime error", "3\n", 1, expected("3"), "", model.WrongAnswer},
+		{"no expected output", "3\n", 0, nil, "", model.NotJudged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, _ := fakeExecutor(t, model.Python3, answer(tt.code, tt.stdout))
+			output := executor.Execute(model.SourceCode{
+				Language:   model.Python3,
+				Content:    "print(x)",
+				Input:      "x = 3",
+				Expected:   tt.expected,
+				Comparator: tt.comparator,
+			}, nil)
+			if output.Verdict != tt.want {
+				t.Errorf("got verdict %q, want %q", output.Verdict, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerdictNeedsEveryCase(t *testing.T) {
+	executor, _ := fakeExecutor(t, model.Python3, answer(0, "1\n", "5\n"))
+	three := "3"
+	output := executor.Execute(model.SourceCode{
+		Language: model.Python3,
+		Content:  "print(x)",
+		Input:    "x = 1\nx = 2\nx = 3",
+		Expected: []*string{nil, &three, nil},
+	}, nil)
+	if output.Verdict != model.WrongAnswer {
+		t.Errorf("got verdict %q, want %q", output.Verdict, model.WrongAnswer)
+	}
+	if got := output.TestCases; got[0].Verdict != model.NotJudged || got[1].Verdict != model.WrongAnswer || got[2].Expected != nil {
+		t.Errorf("got case verdicts %q, %q and %q", got[0].Verdict, got[1].Verdict, got[2].Verdict)
+	}
+}
+
+func TestCustomChecker(t *testing.T) {
+	// The checker run stands in for checker.sh, accepting outputs that
+	// contain the expected one.
+	executor, backend := fakeExecutor(t, model.Python3, func(spec ContainerSpec) FakeRun {
+		if len(spec.TestCases) > 0 {
+			return answer(0, "x=1\n", "x=2\n")(spec)
+		}
+		expectedFiles, _ := filepath.Glob(filepath.Join(spec.HostDir, CasesDir, "*.expected"))
+		for _, path := range expectedFiles {
+			caseFile := strings.TrimSuffix(path, ".expected")
+			expected, _ := os.ReadFile(path)
+			actual, _ := os.ReadFile(caseFile + ".out")
+			code := "1"
+			if strings.Contains(string(actual), string(expected)) {
+				code = "0"
+			}
+			if err := os.WriteFile(caseFile+".check", []byte(code), 0644); err != nil {
+				t.Error(err)
+			}
+		}
+		return FakeRun{}
+	})
+	one, three := "1", "3"
+	output := executor.Execute(model.SourceCode{
+		Language:   model.Python3,
+		Content:    "print(x)",
+		Input:      "x = 1\nx = 2",
+		Expected:   []*string{&one, &three},
+		Comparator: model.CustomComparator,
+		Checker:    "grep -q \"$(cat $2)\" $3",
+	}, nil)
+	if got := output.TestCases; got[0].Verdict != model.Accepted || got[1].Verdict != model.WrongAnswer {
+		t.Errorf("got case verdicts %q and %q", got[0].Verdict, got[1].Verdict)
+	}
+	if output.Verdict != model.WrongAnswer {
+		t.Errorf("got verdict %q, want %q", output.Verdict, model.WrongAnswer)
+	}
+	if runs := backend.Runs(); len(runs) != 2 || runs[1].TimeLimit != 2*checkerCaseTimeout {
+		t.Errorf("got %d runs; the checker should run once, limited to %v", len(runs), 2*checkerCaseTimeout)
+	}
+}
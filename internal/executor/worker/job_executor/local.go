@@ -0,0 +1,281 @@
+//go:build linux
+
+package job_executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// LocalBackend runs code as local processes for machines without Docker. Each
+// run is isolated with bubblewrap (bwrap) in fresh user, pid, network, ipc,
+// uts and cgroup namespaces. Only the language's host paths (see
+// configs.Sandbox.HostPaths) are mounted, read-only, with a private /tmp and
+// spec.HostDir bound at /workdir. Toolchains come from the host: a language's
+// image is not used.
+//
+// With a delegated cgroup v2 subtree (LOCAL_CGROUP_ROOT), every run gets its
+// own cgroup limiting memory, pids and CPU and reporting CPU time and peak
+// memory. Without one, runs are only limited by time and ulimits.
+type LocalBackend struct {
+	bwrap      string
+	cgroupRoot string
+}
+
+// NewLocalBackend finds bwrap and checks that cgroupRoot, when set, is a
+// cgroup v2 directory.
+func NewLocalBackend(cgroupRoot string) (*LocalBackend, error) {
+	bwrap, err := exec.LookPath("bwrap")
+	if err != nil {
+		return nil, fmt.Errorf("the local backend needs bubblewrap: %w", err)
+	}
+	if cgroupRoot == "" {
+		log.Printf("Warning: LOCAL_CGROUP_ROOT is not set, local runs have no memory or pids limit")
+	} else if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.subtree_control")); err != nil {
+		return nil, fmt.Errorf("LOCAL_CGROUP_ROOT %s is not a cgroup v2 directory: %w", cgroupRoot, err)
+	}
+	return &LocalBackend{bwrap: bwrap, cgroupRoot: cgroupRoot}, nil
+}
+
+// Prepare has nothing to do: the host toolchains are used as they are.
+func (b *LocalBackend) Prepare(lang configs.Language) error {
+	return nil
+}
+
+func (b *LocalBackend) Run(spec ContainerSpec, onOutput OutputHandler) JobExecutorOutput {
+	var cgroup *localCgroup
+	if b.cgroupRoot != "" {
+		var err error
+		cgroup, err = newLocalCgroup(b.cgroupRoot, spec.MemoryLimit, spec.Language.Sandbox.PidsLimit)
+		if err != nil {
+			return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to create cgroup: %v", err)}
+		}
+		defer cgroup.remove()
+	}
+
+	streams := newOutputStreams(spec, onOutput)
+	ctx, cancel := spec.runContext()
+	defer cancel()
+	// Killing bwrap takes down its pid namespace, and with it everything
+	// the run started.
+	cmd := exec.CommandContext(ctx, b.bwrap, b.args(spec, cgroup)...)
+	cmd.WaitDelay = time.Second
+	cmd.Stdout, cmd.Stderr = streams.stdout, streams.stderr
+	if cgroup != nil {
+		// Start bwrap, and so everything it runs, inside the cgroup.
+		cmd.SysProcAttr = &syscall.SysProcAttr{UseCgroupFD: true, CgroupFD: cgroup.fd}
+	}
+	// Copy stdin ourselves: Wait would otherwise wait for it to end, long
+	// after the program has exited.
+	var stdin io.WriteCloser
+	if spec.Stdin != nil {
+		var err error
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to attach stdin: %v", err)}
+		}
+	}
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		if stdin != nil {
+			go func() {
+				_, _ = io.Copy(stdin, spec.Stdin)
+				_ = stdin.Close()
+			}()
+		}
+		err = cmd.Wait()
+	}
+	runTime := time.Since(start).Milliseconds()
+	streams.flush()
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		exitCode = timeoutStatusCode
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			exitCode = 128 + int(status.Signal())
+		}
+	case err != nil:
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to start sandbox: %v", err)}
+	}
+
+	var usage sandboxUsage
+	if cgroup != nil {
+		usage = cgroup.usage()
+	}
+	return collectOutput(b, spec, exitCode, runTime, streams, usage)
+}
+
+// args returns bwrap's arguments for spec: the sandbox, then spec.Cmd under
+// the language's ulimits.
+func (b *LocalBackend) args(spec ContainerSpec, cgroup *localCgroup) []string {
+	sandbox := spec.Language.Sandbox
+	args := []string{
+		"--die-with-parent", "--new-session",
+		"--unshare-all", "--unshare-user",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--bind", spec.HostDir, sandboxWorkdir,
+		"--chdir", sandboxWorkdir,
+		"--setenv", "HOME", "/tmp",
+		"--cap-drop", "ALL",
+	}
+	args = append(args, hostPathArgs(sandbox.HostPaths)...)
+	if sandbox.Network {
+		args = append(args, "--share-net")
+	}
+	if uid, gid, ok := strings.Cut(sandbox.User, ":"); ok {
+		args = append(args, "--uid", uid, "--gid", gid)
+	}
+	// The runner reads CPU time from /sys/fs/cgroup, which must show the
+	// run's own cgroup rather than the host's root.
+	if cgroup != nil {
+		args = append(args, "--ro-bind", cgroup.path, "/sys/fs/cgroup")
+	} else {
+		args = append(args, "--tmpfs", "/sys/fs/cgroup")
+	}
+	args = append(args, "--", "sh", "-c", ulimitScript(sandbox.Ulimits)+`exec "$@"`, "sh")
+	return append(args, spec.Cmd...)
+}
+
+// hostPathArgs mounts paths read-only at the same place. Symlinks, like /bin
+// on merged-/usr hosts, are recreated as symlinks; missing paths are skipped.
+func hostPathArgs(paths []string) []string {
+	var args []string
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(path); err == nil {
+				args = append(args, "--symlink", target, path)
+			}
+			continue
+		}
+		args = append(args, "--ro-bind", path, path)
+	}
+	return args
+}
+
+// ulimitFlags maps the ulimit names of the registry to sh's ulimit flags and
+// the unit sh counts them in.
+var ulimitFlags = map[string]struct {
+	flag string
+	unit int64
+}{
+	"nofile": {"-n", 1},
+	"core":   {"-c", 512},
+	"fsize":  {"-f", 512},
+	"stack":  {"-s", 1024},
+	"cpu":    {"-t", 1},
+}
+
+// ulimitScript returns the ulimit commands that apply ulimits in sh. Limits
+// sh has no flag for are skipped.
+func ulimitScript(ulimits map[string]int64) string {
+	names := make([]string, 0, len(ulimits))
+	for name := range ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var script strings.Builder
+	for _, name := range names {
+		limit, ok := ulimitFlags[name]
+		if !ok {
+			log.Printf("Warning: ulimit %s is not supported by the local backend", name)
+			continue
+		}
+		fmt.Fprintf(&script, "ulimit %s %d; ", limit.flag, ulimits[name]/limit.unit)
+	}
+	return script.String()
+}
+
+// localCgroup is the cgroup of one local run.
+type localCgroup struct {
+	path string
+	fd   int
+}
+
+// newLocalCgroup creates a cgroup under root limited to memoryLimit bytes,
+// pidsLimit processes and one CPU core.
+func newLocalCgroup(root string, memoryLimit, pidsLimit int64) (*localCgroup, error) {
+	path, err := os.MkdirTemp(root, "run-")
+	if err != nil {
+		return nil, err
+	}
+	cgroup := &localCgroup{path: path, fd: -1}
+	limits := []struct {
+		file, value string
+		required    bool
+	}{
+		{"memory.max", strconv.FormatInt(memoryLimit, 10), true},
+		{"pids.max", strconv.FormatInt(pidsLimit, 10), true},
+		{"memory.swap.max", "0", false},
+		{"cpu.max", "100000 100000", false}, // 1 CPU core
+	}
+	for _, limit := range limits {
+		err := os.WriteFile(filepath.Join(path, limit.file), []byte(limit.value), 0644)
+		if err != nil && limit.required {
+			cgroup.remove()
+			return nil, fmt.Errorf("failed to set %s (is the controller enabled in %s/cgroup.subtree_control?): %w", limit.file, root, err)
+		}
+	}
+	if cgroup.fd, err = syscall.Open(path, syscall.O_DIRECTORY|syscall.O_RDONLY, 0); err != nil {
+		cgroup.remove()
+		return nil, err
+	}
+	return cgroup, nil
+}
+
+// usage reads the cgroup's peak memory and whether the OOM killer fired in
+// it. Both are best effort.
+func (c *localCgroup) usage() sandboxUsage {
+	var usage sandboxUsage
+	if data, err := os.ReadFile(filepath.Join(c.path, "memory.peak")); err == nil {
+		if peak, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			usage.peakMemory = peak >> 10
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(c.path, "memory.events")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if count, ok := strings.CutPrefix(line, "oom_kill "); ok {
+				usage.oomKilled = count != "0"
+			}
+		}
+	}
+	return usage
+}
+
+// remove kills whatever is left in the cgroup and removes it.
+func (c *localCgroup) remove() {
+	if c.fd >= 0 {
+		syscall.Close(c.fd)
+	}
+	_ = os.WriteFile(filepath.Join(c.path, "cgroup.kill"), []byte("1"), 0644)
+	// The killed processes take a moment to leave the cgroup.
+	var err error
+	for range 50 {
+		if err = os.Remove(c.path); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	log.Printf("Warning: failed to remove cgroup %s: %v", c.path, err)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package job_executor
+
+import "errors"
+
+// NewLocalBackend fails outside Linux: the local backend is built on Linux
+// namespaces and cgroups.
+func NewLocalBackend(cgroupRoot string) (ExecutionBackend, error) {
+	return nil, errors.New("the local backend needs Linux")
+}
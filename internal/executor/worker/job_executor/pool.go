@@ -0,0 +1,196 @@
+package job_executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// poolLabel marks pooled containers so ones left behind by a previous run of
+// the server can be found and removed.
+const poolLabel = "go-ide-pair.pool"
+
+// resetScript kills whatever the previous run left behind — every process of
+// the sandbox user but the shell itself — and empties the tmpfs mounts.
+const resetScript = "kill -9 -1 2>/dev/null; find " + sandboxWorkdir + " /tmp -mindepth 1 -delete"
+
+// ContainerPool keeps idle, pre-started sandbox containers of one image. A
+// run takes one of them instead of creating its own, which skips the
+// create/start/remove of a cold run. A container is reset and put back after
+// each run until it has served maxReuse runs.
+type ContainerPool struct {
+	backend   *DockerBackend
+	image     string
+	resources container.Resources
+	sandbox   configs.Sandbox
+	maxReuse  int
+	idle      chan *pooledContainer
+}
+
+type pooledContainer struct {
+	id     string
+	uses   int
+	memory int64
+	// peak is the highest peak memory, in KB, the container's cgroup has
+	// reported after a run.
+	peak int64
+	// retired containers are removed instead of being put back.
+	retired bool
+}
+
+// NewContainerPool returns a pool of up to size idle containers of image,
+// created with resources and hardened as sandbox asks. It starts empty; see
+// Fill.
+func NewContainerPool(backend *DockerBackend, image string, resources container.Resources, sandbox configs.Sandbox, size, maxReuse int) *ContainerPool {
+	return &ContainerPool{
+		backend:   backend,
+		image:     image,
+		resources: resources,
+		sandbox:   sandbox,
+		maxReuse:  maxReuse,
+		idle:      make(chan *pooledContainer, size),
+	}
+}
+
+// RemovePoolContainers removes the pooled containers of a previous run of the
+// server.
+func RemovePoolContainers(cli *client.Client) {
+	ctx := context.Background()
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", poolLabel)),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to list pooled containers: %v", err)
+		return
+	}
+	for _, c := range containers {
+		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+			log.Printf("Warning: failed to remove container %s: %v", c.ID, err)
+		}
+	}
+}
+
+// Fill starts containers until the pool holds size idle ones.
+func (p *ContainerPool) Fill() error {
+	for len(p.idle) < cap(p.idle) {
+		c, err := p.create()
+		if err != nil {
+			return err
+		}
+		p.put(c)
+	}
+	return nil
+}
+
+// Run runs spec in a pooled container, streaming its output to onOutput.
+func (p *ContainerPool) Run(spec ContainerSpec, onOutput OutputHandler) JobExecutorOutput {
+	c, err := p.get()
+	if err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to start container: %v", err)}
+	}
+	defer p.release(c)
+
+	if spec.MemoryLimit != c.memory {
+		// Keep the default swap allowance of a cold container: as much again.
+		resources := container.Resources{Memory: spec.MemoryLimit, MemorySwap: 2 * spec.MemoryLimit}
+		if _, err := p.backend.cli.ContainerUpdate(context.Background(), c.id, container.UpdateConfig{Resources: resources}); err != nil {
+			return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to update container limits: %v", err)}
+		}
+		c.memory = spec.MemoryLimit
+	}
+	output, expired := p.backend.runInSandbox(c.id, spec, onOutput)
+	if expired {
+		// What the run left running could outlive a reset, e.g. a fork bomb
+		// holding every pid the container may have.
+		c.retired = true
+	}
+	// The cgroup's peak memory lasts for the container's lifetime and can't
+	// be reset from inside it: the sandbox sees /sys/fs/cgroup read-only,
+	// and writing memory.peak only resets it for the writer's own file. A
+	// peak is this run's only when it is higher than any before; otherwise
+	// the run's peak is unknown.
+	if peak := output.PeakMemory; peak > c.peak {
+		c.peak = peak
+	} else {
+		output.PeakMemory = 0
+	}
+	// The OOM flag lasts as long. Retire a container once its runs have used
+	// much of its memory, so the flag stays meaningful for the runs after it.
+	if c.peak<<10 > c.memory/2 {
+		c.retired = true
+	}
+	return output
+}
+
+// get takes an idle container, or starts one when the pool is empty.
+func (p *ContainerPool) get() (*pooledContainer, error) {
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+		return p.create()
+	}
+}
+
+// release resets c and puts it back. Once c has been used maxReuse times, is
+// retired or cannot be reset, it is removed and replaced in the background.
+func (p *ContainerPool) release(c *pooledContainer) {
+	c.uses++
+	if c.uses < p.maxReuse && !c.retired {
+		err := p.reset(c)
+		if err == nil {
+			p.put(c)
+			return
+		}
+		log.Printf("Warning: failed to reset container %s: %v", c.id, err)
+	}
+	p.remove(c)
+	go func() {
+		if err := p.Fill(); err != nil {
+			log.Printf("Warning: failed to refill the %s pool: %v", p.image, err)
+		}
+	}()
+}
+
+// put adds c to the idle containers, or removes it when the pool is full.
+func (p *ContainerPool) put(c *pooledContainer) {
+	select {
+	case p.idle <- c:
+	default:
+		p.remove(c)
+	}
+}
+
+func (p *ContainerPool) create() (*pooledContainer, error) {
+	id, err := createSandbox(p.backend.cli, p.image, p.resources, p.sandbox, map[string]string{poolLabel: p.image})
+	if err != nil {
+		return nil, err
+	}
+	return &pooledContainer{id: id, memory: p.resources.Memory}, nil
+}
+
+// reset runs resetScript in c and checks that it succeeded — which also
+// shows that the container is still running.
+func (p *ContainerPool) reset(c *pooledContainer) error {
+	code, err := execWithTimeout(p.backend.cli, c.id, p.sandbox.User, []string{"sh", "-c", resetScript}, nil, io.Discard, io.Discard)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("reset exited with %d", code)
+	}
+	return nil
+}
+
+func (p *ContainerPool) remove(c *pooledContainer) {
+	if err := p.backend.cli.ContainerRemove(context.Background(), c.id, container.RemoveOptions{Force: true}); err != nil {
+		log.Printf("Warning: failed to remove container %s: %v", c.id, err)
+	}
+}
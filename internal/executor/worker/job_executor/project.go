@@ -0,0 +1,101 @@
+package job_executor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// runnerFiles are the working directory entries the runner and the judge
+// write; project files may not use them.
+var runnerFiles = []string{"runner.sh", CasesDir, "compile_output.txt", CompileMetaFile, "checker.sh"}
+
+// ValidateFilePath checks that filePath can name a project file: see
+// model.ValidateFilePath. It must also not clash with the runner's files.
+func ValidateFilePath(filePath string) error {
+	if err := model.ValidateFilePath(filePath); err != nil {
+		return err
+	}
+	if first, _, _ := strings.Cut(filePath, "/"); slices.Contains(runnerFiles, first) {
+		return fmt.Errorf("file path %s is reserved for the runner", filePath)
+	}
+	return nil
+}
+
+// projectFiles returns the files of source by path and the path of its
+// entrypoint. Without Files, the project is Content written to
+// lang.SourceFile.
+func projectFiles(lang configs.Language, source model.SourceCode) (map[string]string, string) {
+	if len(source.Files) == 0 {
+		return map[string]string{lang.SourceFile: source.Content}, lang.SourceFile
+	}
+	entrypoint := source.Entrypoint
+	if entrypoint == "" {
+		entrypoint = lang.SourceFile
+	}
+	return source.Files, entrypoint
+}
+
+// validateProject checks the files and entrypoint of source.
+func validateProject(lang configs.Language, source model.SourceCode) error {
+	if err := source.ValidateFiles(); err != nil {
+		return err
+	}
+	files, entrypoint := projectFiles(lang, source)
+	if _, ok := files[entrypoint]; !ok {
+		return fmt.Errorf("the project has no %s to run", entrypoint)
+	}
+	for filePath := range files {
+		if err := ValidateFilePath(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeProject writes files into dir, creating their directories.
+func writeProject(dir string, files map[string]string) error {
+	for filePath, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(filePath))
+		if err := os.MkdirAll(filepath.Dir(target), fs.FileMode(0755)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(content), fs.FileMode(0644)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProjectCommand replaces the project placeholders of a registry command:
+//
+//	{entrypoint}  the entrypoint's path, e.g. app/Main.java
+//	{module}      the entrypoint's path without extension, dot-separated,
+//	              e.g. app.Main
+//	{sources}     every file with the entrypoint's extension, e.g. the
+//	              .c files of a C project
+//
+// Paths are checked by ValidateFilePath, so they need no quoting.
+func ProjectCommand(cmd, entrypoint string, files map[string]string) string {
+	ext := path.Ext(entrypoint)
+	var sources []string
+	for filePath := range files {
+		if filePath == entrypoint || ext != "" && path.Ext(filePath) == ext {
+			sources = append(sources, filePath)
+		}
+	}
+	slices.Sort(sources)
+	module := strings.ReplaceAll(strings.TrimSuffix(entrypoint, ext), "/", ".")
+	return strings.NewReplacer(
+		"{entrypoint}", entrypoint,
+		"{module}", module,
+		"{sources}", strings.Join(sources, " "),
+	).Replace(cmd)
+}
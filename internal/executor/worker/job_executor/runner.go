@@ -0,0 +1,116 @@
+package job_executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// CompileMetaFile is where runner scripts record the wall time of the compile
+// step, in ms.
+const CompileMetaFile = "compile.meta"
+
+// shellRunnerScript is the template behind ShellRunner. stdout is streamed
+// through tee and kept in cases/<i>.out; stderr goes to cases/<i>.err and is
+// replayed once the case ends. Both files are capped by ulimit -f well above
+// MaxStdoutBytes and MaxStderrBytes, which stops a program flooding its
+// output — the bytes are counted when the files are read back.
+//
+// Times are measured inside the sandbox: wall time with date, CPU time
+// (user+sys) from the container's cgroup, in which nothing else runs.
+const shellRunnerScript = `#!/bin/sh
+now_ms() { echo $(( $(date +%%s%%N) / 1000000 )); }
+cpu_ms() {
+    if [ -r /sys/fs/cgroup/cpu.stat ]; then
+        while read -r key value; do
+            case "$key" in
+                user_usec) user=$value ;;
+                system_usec) system=$value ;;
+            esac
+        done < /sys/fs/cgroup/cpu.stat
+        echo $(( (user + system) / 1000 ))
+    elif [ -r /sys/fs/cgroup/cpuacct/cpuacct.usage ]; then
+        echo $(( $(cat /sys/fs/cgroup/cpuacct/cpuacct.usage) / 1000000 ))
+    else
+        echo 0
+    fi
+}
+%s
+i=1
+while [ -f "cases/$i.in" ]; do
+    case_file="cases/$i"
+    start=$(now_ms); cpu_start=$(cpu_ms)
+    { (ulimit -f 64; timeout %gs %s < "$case_file.in" 2> "$case_file.err"); echo $? > "$case_file.code"; } \
+        | (ulimit -f 64; tee "$case_file.out")
+    end=$(now_ms); cpu_end=$(cpu_ms)
+    echo "$(cat "$case_file.code") $((end - start)) $((cpu_end - cpu_start))" > "$case_file.meta"
+    cat "$case_file.err" >&2
+    i=$((i + 1))
+done
+`
+
+// shellCompileStep is the compile part of shellRunnerScript.
+const shellCompileStep = `start=$(now_ms)
+%s > compile_output.txt 2>&1
+status=$?
+echo $(( $(now_ms) - start )) > ` + CompileMetaFile + `
+if [ $status -ne 0 ]; then
+    exit %d
+fi`
+
+// ShellRunner returns a runner.sh that runs compileCmd, if any, with its
+// output in compile_output.txt — exiting compileErrorCode when it fails —
+// and then feeds each cases/<i>.in to runCmd on stdin, writing the
+// per-case files readTestCaseResults expects.
+func ShellRunner(compileCmd, runCmd string, caseTimeout time.Duration, compileErrorCode int) string {
+	compile := ""
+	if compileCmd != "" {
+		compile = fmt.Sprintf(shellCompileStep, compileCmd, compileErrorCode)
+	}
+	return fmt.Sprintf(shellRunnerScript, compile, caseTimeout.Seconds(), runCmd)
+}
+
+// interactiveRunnerScript is the template behind InteractiveRunner.
+const interactiveRunnerScript = `#!/bin/sh
+now_ms() { echo $(( $(date +%%s%%N) / 1000000 )); }
+%s
+exec %s
+`
+
+// InteractiveRunner returns a runner.sh that compiles like ShellRunner and
+// then runs runCmd once, on the runner's own stdin and stdout.
+func InteractiveRunner(compileCmd, runCmd string, compileErrorCode int) string {
+	compile := ""
+	if compileCmd != "" {
+		compile = fmt.Sprintf(shellCompileStep, compileCmd, compileErrorCode)
+	}
+	return fmt.Sprintf(interactiveRunnerScript, compile, runCmd)
+}
+
+// PullImage pre-pulls ref so first executions aren't slow. Images that are
+// already present are used as they are, which also allows locally built ones.
+// The response body MUST be fully drained before closing — otherwise Docker
+// cancels the download mid-flight and the image is never stored locally.
+func PullImage(cli *client.Client, ref string) error {
+	ctx := context.Background()
+	if _, _, err := cli.ImageInspectWithRaw(ctx, ref); err == nil {
+		log.Printf("Image %s ready.", ref)
+		return nil
+	}
+	log.Printf("Pulling image %s (this may take a minute on first run) ...", ref)
+	out, err := cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(io.Discard, out); err != nil {
+		log.Printf("Warning: error reading image pull stream: %v", err)
+	}
+	log.Printf("Image %s ready.", ref)
+	return nil
+}
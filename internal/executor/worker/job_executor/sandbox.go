@@ -0,0 +1,327 @@
+package job_executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// sandboxWorkdir is where code runs: a size-limited tmpfs in every sandbox.
+const sandboxWorkdir = "/workdir"
+
+// helperExecTimeout bounds the execs that copy files and read usage around
+// a run. They only take this long when the sandbox is wedged.
+const helperExecTimeout = 30 * time.Second
+
+// maxCopiedFileBytes caps each file copied back out of a sandbox. The runner
+// already caps what it writes, so this only guards the host disk.
+const maxCopiedFileBytes = 1 << 20
+
+// createSandbox creates and starts an idle container of image, hardened as
+// sandbox asks, and returns its ID. Code never runs as the container's main
+// process: the idle root process only keeps the container alive, and each
+// step of a run is a docker exec as sandbox.User — so a run can kill all of
+// its processes without stopping the container. --init reaps what it
+// orphans.
+func createSandbox(cli *client.Client, image string, resources container.Resources, sandbox configs.Sandbox, labels map[string]string) (string, error) {
+	ctx := context.Background()
+	resp, err := cli.ContainerCreate(ctx, &container.Config{
+		Image:      image,
+		WorkingDir: sandboxWorkdir,
+		Cmd:        []string{"sleep", "infinity"},
+		Env:        []string{"HOME=/tmp"},
+		Labels:     labels,
+	}, sandboxHostConfig(resources, sandbox), nil, nil, "")
+	if err != nil {
+		return "", err
+	}
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		_ = cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// sandboxHostConfig turns sandbox into the container's host configuration.
+func sandboxHostConfig(resources container.Resources, sandbox configs.Sandbox) *container.HostConfig {
+	init := true
+	resources.PidsLimit = &sandbox.PidsLimit
+	names := make([]string, 0, len(sandbox.Ulimits))
+	for name := range sandbox.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		limit := sandbox.Ulimits[name]
+		resources.Ulimits = append(resources.Ulimits, &units.Ulimit{Name: name, Soft: limit, Hard: limit})
+	}
+
+	hostConfig := &container.HostConfig{
+		Resources:      resources,
+		Init:           &init,
+		ReadonlyRootfs: !sandbox.WritableRootfs,
+		CapDrop:        []string{"ALL"},
+		CapAdd:         sandbox.CapAdd,
+		Tmpfs: map[string]string{
+			sandboxWorkdir: fmt.Sprintf("rw,exec,nosuid,nodev,mode=1777,size=%dm", sandbox.WorkdirSize),
+			"/tmp":         fmt.Sprintf("rw,exec,nosuid,nodev,mode=1777,size=%dm", sandbox.TmpSize),
+		},
+	}
+	if !sandbox.Network {
+		hostConfig.NetworkMode = "none"
+	}
+	if !sandbox.AllowNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+	}
+	if sandbox.Seccomp != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+sandbox.Seccomp)
+	}
+	return hostConfig
+}
+
+// runInSandbox copies spec.HostDir into the sandbox, runs spec.Cmd there
+// while streaming its output to onOutput, and copies the compile output and
+// CasesDir back, so the result is read from spec.HostDir.
+//
+// A run still going killGracePeriod after spec.TimeLimit is cut off and
+// reported as RuntimeTimeout; expired is then set, and the container must
+// be removed rather than reused, as the run's processes may still be in it.
+//
+// Files travel as tar streams through docker exec: the copy API cannot reach
+// into a tmpfs mount.
+func (b *DockerBackend) runInSandbox(id string, spec ContainerSpec, onOutput OutputHandler) (output JobExecutorOutput, expired bool) {
+	cli := b.cli
+	user := spec.Language.Sandbox.User
+
+	var tarErr bytes.Buffer
+	code, err := execWithTimeout(cli, id, user, []string{"tar", "-x", "-C", sandboxWorkdir}, tarDir(spec.HostDir), io.Discard, &tarErr)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("tar exited with %d: %s", code, tarErr.String())
+	}
+	if err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to copy files into container: %v", err)}, false
+	}
+
+	streams := newOutputStreams(spec, onOutput)
+	ctx, cancel := spec.runContext()
+	defer cancel()
+	start := time.Now()
+	exitCode, err := execIn(ctx, cli, id, user, spec.Cmd, spec.Stdin, streams.stdout, streams.stderr)
+	runTime := time.Since(start).Milliseconds()
+	streams.flush()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return JobExecutorOutput{
+			Status:          RuntimeTimeout,
+			ExitCode:        timeoutStatusCode,
+			RunTime:         runTime,
+			Stdout:          streams.stdout.buf.String(),
+			Stderr:          streams.stderr.buf.String(),
+			StdoutTruncated: streams.stdout.truncated,
+			StderrTruncated: streams.stderr.truncated,
+		}, true
+	}
+	if err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to run in container: %v", err)}, false
+	}
+
+	paths := []string{CasesDir}
+	if spec.CompileOutputFile != "" {
+		paths = append(paths, spec.CompileOutputFile, CompileMetaFile)
+	}
+	if err := copyOut(cli, id, user, paths, spec.HostDir); err != nil {
+		return JobExecutorOutput{Status: RuntimeError, Stderr: fmt.Sprintf("Failed to copy files out of container: %v", err)}, false
+	}
+	return collectOutput(b, spec, exitCode, runTime, streams, readUsage(cli, id, user)), false
+}
+
+// peakMemoryScript prints the peak memory usage of the container's cgroup in
+// bytes, under cgroup v2 or v1.
+const peakMemoryScript = "cat /sys/fs/cgroup/memory.peak 2>/dev/null || cat /sys/fs/cgroup/memory/memory.max_usage_in_bytes"
+
+// sandboxUsage is what the container reports about a finished run.
+type sandboxUsage struct {
+	peakMemory int64 // KB, 0 when unknown
+	oomKilled  bool
+}
+
+// readUsage reads the container's peak memory and whether the kernel OOM
+// killed a process in it. Both are best effort.
+func readUsage(cli *client.Client, id, user string) sandboxUsage {
+	var usage sandboxUsage
+	var out bytes.Buffer
+	if code, err := execWithTimeout(cli, id, user, []string{"sh", "-c", peakMemoryScript}, nil, &out, io.Discard); err == nil && code == 0 {
+		if peak, err := strconv.ParseInt(strings.TrimSpace(out.String()), 10, 64); err == nil {
+			usage.peakMemory = peak >> 10
+		}
+	}
+	if inspect, err := cli.ContainerInspect(context.Background(), id); err == nil && inspect.State != nil {
+		usage.oomKilled = inspect.State.OOMKilled
+	} else if err != nil {
+		log.Printf("Warning: failed to inspect container %s: %v", id, err)
+	}
+	return usage
+}
+
+// copyOut extracts paths, relative to the sandbox workdir, into hostDir.
+// Paths the run never created are skipped.
+func copyOut(cli *client.Client, id, user string, paths []string, hostDir string) error {
+	cmd := append([]string{"sh", "-c", `tar -c -C ` + sandboxWorkdir + ` "$@" 2>/dev/null`, "sh"}, paths...)
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := execWithTimeout(cli, id, user, cmd, nil, pw, io.Discard)
+		pw.CloseWithError(err)
+	}()
+	err := untar(pr, hostDir)
+	// Let the exec finish even if the archive ended early.
+	_, _ = io.Copy(io.Discard, pr)
+	return err
+}
+
+// execIn runs cmd in the container as user, feeding it stdin when set, and
+// returns its exit code once it has finished. Once ctx is done it stops
+// waiting and returns ctx.Err(); cmd, or what it started, may still be
+// running.
+func execIn(ctx context.Context, cli *client.Client, id, user string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	execResp, err := cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		User:         user,
+		Cmd:          cmd,
+		WorkingDir:   sandboxWorkdir,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	attachResp, err := cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer attachResp.Close()
+	// The stream stays open while anything holds cmd's stdout, including
+	// children that outlive it, so closing it is what ends the wait.
+	stop := context.AfterFunc(ctx, attachResp.Close)
+	defer stop()
+
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(attachResp.Conn, stdin)
+			_ = attachResp.CloseWrite()
+		}()
+	}
+	// The attach stream closes once cmd has exited.
+	if _, err := stdcopy.StdCopy(stdout, stderr, attachResp.Reader); err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, err
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, execResp.ID)
+	for err == nil && inspect.Running {
+		time.Sleep(10 * time.Millisecond)
+		inspect, err = cli.ContainerExecInspect(ctx, execResp.ID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
+// execWithTimeout is execIn limited to helperExecTimeout.
+func execWithTimeout(cli *client.Client, id, user string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), helperExecTimeout)
+	defer cancel()
+	return execIn(ctx, cli, id, user, cmd, stdin, stdout, stderr)
+}
+
+// tarDir returns a tar archive of the contents of dir.
+func tarDir(dir string) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || path == dir {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// untar extracts the regular files and directories of a tar stream into dir,
+// each file capped at maxCopiedFileBytes.
+func untar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, header.Name)
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %q escapes the workdir", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, io.LimitReader(tr, maxCopiedFileBytes))
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package job_executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// dockerExecutor returns a Python executor on a Docker backend with short
+// time limits, skipping the test when no Docker daemon is reachable. Its
+// runs use cold containers, so nothing is left behind and the pools of a
+// server running on the same daemon are left alone.
+func dockerExecutor(t *testing.T) *LanguageJobExecutor {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("sandbox tests start containers")
+	}
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("Docker is not available: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(ctx); err != nil {
+		t.Skipf("Docker is not available: %v", err)
+	}
+
+	lang, ok := configs.GetLanguage(model.Python3)
+	if !ok {
+		t.Fatal("Python 3 is not in the language registry")
+	}
+	lang.TimeLimit, lang.TotalTimeLimit = 2000, 5000
+	backend := &DockerBackend{
+		cli:       cli,
+		languages: map[model.ProgrammingLanguage]*dockerLanguage{lang.ID: {}},
+	}
+	if err := backend.Prepare(lang); err != nil {
+		t.Fatalf("failed to pull %s: %v", lang.Image, err)
+	}
+	return NewLanguageJobExecutor(backend, lang)
+}
+
+// executeWithin runs content and fails the test if the run has not ended
+// within limit.
+func executeWithin(t *testing.T, executor *LanguageJobExecutor, content string, limit time.Duration) JobExecutorOutput {
+	t.Helper()
+	done := make(chan JobExecutorOutput, 1)
+	go func() {
+		done <- executor.Execute(model.SourceCode{Language: executor.lang.ID, Content: content}, nil)
+	}()
+	select {
+	case output := <-done:
+		return output
+	case <-time.After(limit):
+		t.Fatalf("the run was still going after %v", limit)
+		return JobExecutorOutput{}
+	}
+}
+
+// runLimit is how long a run of executor may take at most: its total time
+// limit, the grace period after it and the execs around the run.
+func runLimit(executor *LanguageJobExecutor) time.Duration {
+	return time.Duration(executor.lang.TotalTimeLimit)*time.Millisecond + killGracePeriod + 3*helperExecTimeout
+}
+
+func TestForkBombEndsCleanly(t *testing.T) {
+	executor := dockerExecutor(t)
+	output := executeWithin(t, executor, `import os
+while True:
+    try:
+        os.fork()
+    except OSError:
+        pass
+`, runLimit(executor))
+	if output.Status != RuntimeTimeout && output.Status != RuntimeError {
+		t.Errorf("a fork bomb ended with status %d, want a timeout or runtime error; stderr: %s", output.Status, output.Stderr)
+	}
+
+	// The backend must still run code afterwards.
+	output = executeWithin(t, executor, `print("ok")`, runLimit(executor))
+	if output.Status != Successful || output.Stdout != "ok\n" {
+		t.Errorf("after a fork bomb a run ended with status %d, stdout %q, stderr %q", output.Status, output.Stdout, output.Stderr)
+	}
+}
+
+func TestNetworkIsUnreachable(t *testing.T) {
+	executor := dockerExecutor(t)
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"connect", "import socket\nsocket.create_connection((\"1.1.1.1\", 53), timeout=3)\nprint(\"connected\")\n"},
+		{"resolve", "import socket\nsocket.getaddrinfo(\"example.com\", 80)\nprint(\"connected\")\n"},
+		{"http", "import urllib.request\nurllib.request.urlopen(\"http://example.com\", timeout=3)\nprint(\"connected\")\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := executeWithin(t, executor, tt.content, runLimit(executor))
+			if output.Status != RuntimeError || strings.Contains(output.Stdout, "connected") {
+				t.Errorf("ended with status %d, stdout %q, stderr %q; want a runtime error", output.Status, output.Stdout, output.Stderr)
+			}
+		})
+	}
+}
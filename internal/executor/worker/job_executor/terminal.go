@@ -0,0 +1,134 @@
+package job_executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// Terminal is a shell on a PTY in a long-lived sandbox. Reads return what
+// the terminal displays and end with io.EOF once the shell exits; writes are
+// typed into it.
+type Terminal interface {
+	io.ReadWriter
+	Resize(rows, cols uint) error
+	// Close destroys the sandbox.
+	Close() error
+}
+
+// TerminalSpec describes the sandbox of a terminal.
+type TerminalSpec struct {
+	Image       string
+	MemoryLimit int64 // in bytes
+	Sandbox     configs.Sandbox
+	// Files are written to /workdir before the shell starts, by path.
+	Files map[string]string
+}
+
+// TerminalBackend is implemented by backends that can open terminals.
+type TerminalBackend interface {
+	OpenTerminal(spec TerminalSpec) (Terminal, error)
+}
+
+// terminalShell starts bash where the image has it, sh otherwise.
+const terminalShell = "if command -v bash >/dev/null; then exec bash; else exec sh; fi"
+
+// OpenTerminal starts a sandbox container of spec.Image and a shell in it on
+// a PTY. The container is labelled like pooled ones, so a terminal left
+// behind by a crash is removed on the next start.
+func (b *DockerBackend) OpenTerminal(spec TerminalSpec) (Terminal, error) {
+	if err := PullImage(b.cli, spec.Image); err != nil {
+		return nil, err
+	}
+	id, err := createSandbox(b.cli, spec.Image, resourcesFor(spec.MemoryLimit), spec.Sandbox, map[string]string{poolLabel: "terminal"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	t := &dockerTerminal{cli: b.cli, containerID: id}
+	if err := t.start(spec); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+type dockerTerminal struct {
+	cli         *client.Client
+	containerID string
+	execID      string
+	conn        types.HijackedResponse
+}
+
+func (t *dockerTerminal) start(spec TerminalSpec) error {
+	if len(spec.Files) > 0 {
+		dir, err := os.MkdirTemp("", "terminal")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := writeProject(dir, spec.Files); err != nil {
+			return err
+		}
+		code, err := execWithTimeout(t.cli, t.containerID, spec.Sandbox.User, []string{"tar", "-x", "-C", sandboxWorkdir}, tarDir(dir), io.Discard, io.Discard)
+		if err == nil && code != 0 {
+			err = fmt.Errorf("tar exited with %d", code)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to copy files into container: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+	size := [2]uint{24, 80}
+	execResp, err := t.cli.ContainerExecCreate(ctx, t.containerID, container.ExecOptions{
+		User:         spec.Sandbox.User,
+		Tty:          true,
+		ConsoleSize:  &size,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          []string{"TERM=xterm-256color", "HOME=/tmp"},
+		WorkingDir:   sandboxWorkdir,
+		Cmd:          []string{"sh", "-c", terminalShell},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start shell: %w", err)
+	}
+	t.execID = execResp.ID
+	// With a TTY the stream is raw rather than multiplexed.
+	t.conn, err = t.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{Tty: true, ConsoleSize: &size})
+	if err != nil {
+		return fmt.Errorf("failed to attach to shell: %w", err)
+	}
+	return nil
+}
+
+func (t *dockerTerminal) Read(p []byte) (int, error) {
+	return t.conn.Reader.Read(p)
+}
+
+func (t *dockerTerminal) Write(p []byte) (int, error) {
+	return t.conn.Conn.Write(p)
+}
+
+func (t *dockerTerminal) Resize(rows, cols uint) error {
+	return t.cli.ContainerExecResize(context.Background(), t.execID, container.ResizeOptions{Height: rows, Width: cols})
+}
+
+func (t *dockerTerminal) Close() error {
+	if t.conn.Conn != nil {
+		t.conn.Close()
+	}
+	err := t.cli.ContainerRemove(context.Background(), t.containerID, container.RemoveOptions{Force: true})
+	if err != nil {
+		log.Printf("Warning: failed to remove container %s: %v", t.containerID, err)
+	}
+	return err
+}
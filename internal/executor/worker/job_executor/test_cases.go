@@ -0,0 +1,217 @@
+package job_executor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// CasesDir is the workdir subdirectory holding per-test-case files. For test
+// case i (1-based) the executor writes <i>.in and the runner writes
+//   - <i>.out, <i>.err — the case's stdout and stderr
+//   - <i>.meta         — "<exit code> <wall time in ms> <cpu time in ms>"
+const CasesDir = "cases"
+
+// TestCaseResult is the outcome of running the program on one test case.
+type TestCaseResult struct {
+	Input           string
+	Stdout          string
+	Stderr          string
+	ExitCode        int
+	RunTime         int64 // wall time, ms
+	CPUTime         int64 // user+sys, ms
+	Status          ExecutionStatus
+	StdoutTruncated bool
+	StderrTruncated bool
+	Expected        *string
+	Verdict         model.Verdict
+}
+
+// SplitTestCases splits the UI input into test cases, one per non-blank line.
+// A program without input still runs once, on a single empty test case.
+func SplitTestCases(raw string) []string {
+	var cases []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			cases = append(cases, line)
+		}
+	}
+	if len(cases) == 0 {
+		return []string{""}
+	}
+	return cases
+}
+
+// StdinTestCases converts the UI input format into one stdin block per test
+// case for languages that read their input: the value of every top-level
+// "key=value" (or bare value) on its own line.
+//
+//	nums=[1,2,4,5], k=3  →  "[1,2,4,5]\n3\n"
+func StdinTestCases(raw string) []string {
+	var groups []string
+	for _, line := range SplitTestCases(raw) {
+		var block strings.Builder
+		for _, tok := range SplitTopLevel(line) {
+			block.WriteString(ExtractValue(tok))
+			block.WriteString("\n")
+		}
+		groups = append(groups, block.String())
+	}
+	return groups
+}
+
+// AssignmentTestCases converts the UI input format into one block of Python
+// assignments per test case, which the runner prepends to the program.
+//
+//	nums=[1,2,4,5], k=3  →  nums=[1,2,4,5]
+//	                        k=3
+//
+// Commas inside brackets are ignored — SplitTopLevel handles nested structures.
+func AssignmentTestCases(raw string) []string {
+	var groups []string
+	for _, line := range SplitTestCases(raw) {
+		var block strings.Builder
+		for _, tok := range SplitTopLevel(line) {
+			block.WriteString(strings.TrimSpace(tok))
+			block.WriteString("\n")
+		}
+		groups = append(groups, block.String())
+	}
+	return groups
+}
+
+// DeclarationTestCases converts the UI input format into one block of
+// JavaScript variable declarations per test case, which the runner prepends
+// to the program — so, as in Python, the code can reference nums, k, etc.
+// directly.
+//
+//	nums=[1,2,4,5], k=3  →  var nums = [1,2,4,5];
+//	                        var k = 3;
+//
+// Bare values are kept as expression statements.
+func DeclarationTestCases(raw string) []string {
+	var groups []string
+	for _, line := range SplitTestCases(raw) {
+		var block strings.Builder
+		for _, tok := range SplitTopLevel(line) {
+			if name, value, ok := strings.Cut(tok, "="); ok {
+				fmt.Fprintf(&block, "var %s = %s;\n", strings.TrimSpace(name), strings.TrimSpace(value))
+			} else {
+				fmt.Fprintf(&block, "%s;\n", strings.TrimSpace(tok))
+			}
+		}
+		groups = append(groups, block.String())
+	}
+	return groups
+}
+
+// SplitTopLevel splits s by comma, ignoring commas nested inside [], {}, or ().
+func SplitTopLevel(s string) []string {
+	var parts []string
+	depth := 0
+	var cur strings.Builder
+	for _, ch := range s {
+		switch ch {
+		case '(', '[', '{':
+			depth++
+			cur.WriteRune(ch)
+		case ')', ']', '}':
+			depth--
+			cur.WriteRune(ch)
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(cur.String()))
+				cur.Reset()
+			} else {
+				cur.WriteRune(ch)
+			}
+		default:
+			cur.WriteRune(ch)
+		}
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, strings.TrimSpace(cur.String()))
+	}
+	return parts
+}
+
+// ExtractValue returns the value of a "key=value" token, or the token itself.
+func ExtractValue(token string) string {
+	token = strings.TrimSpace(token)
+	if i := strings.Index(token, "="); i >= 0 {
+		return strings.TrimSpace(token[i+1:])
+	}
+	return token
+}
+
+// TestCaseInputs converts the UI input into one input per test case in the
+// form mode expects.
+func TestCaseInputs(raw string, mode configs.InputMode) []string {
+	switch mode {
+	case configs.AssignmentsInput:
+		return AssignmentTestCases(raw)
+	case configs.DeclarationsInput:
+		return DeclarationTestCases(raw)
+	default:
+		return StdinTestCases(raw)
+	}
+}
+
+// WriteTestCases writes each language-formatted input to CasesDir/<i>.in.
+func WriteTestCases(dir string, inputs []string) error {
+	casesDir := filepath.Join(dir, CasesDir)
+	if err := os.Mkdir(casesDir, 0755); err != nil {
+		return err
+	}
+	for i, input := range inputs {
+		path := filepath.Join(casesDir, fmt.Sprintf("%d.in", i+1))
+		if err := os.WriteFile(path, []byte(input), fs.FileMode(0644)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTestCaseResults collects what the runner wrote for each test case.
+// inputs are the raw UI lines, reported back as TestCaseResult.Input.
+// A case without a .meta file never ran, e.g. because an earlier case used up
+// the overall time limit.
+func readTestCaseResults(dir string, inputs []string) []TestCaseResult {
+	results := make([]TestCaseResult, len(inputs))
+	for i, input := range inputs {
+		prefix := filepath.Join(dir, CasesDir, fmt.Sprintf("%d", i+1))
+		result := TestCaseResult{Input: input, Status: NotExecuted}
+		result.Stdout, result.StdoutTruncated = readCapped(prefix+".out", MaxStdoutBytes)
+		result.Stderr, result.StderrTruncated = readCapped(prefix+".err", MaxStderrBytes)
+
+		meta, err := os.ReadFile(prefix + ".meta")
+		if err == nil {
+			if _, err := fmt.Sscan(string(meta), &result.ExitCode, &result.RunTime, &result.CPUTime); err == nil {
+				result.Status = statusForExitCode(result.ExitCode, nil)
+				// A flood of output usually ends with the program killed
+				// by SIGPIPE or SIGXFSZ; the output is the real problem.
+				if result.StdoutTruncated || result.StderrTruncated {
+					result.Status = OutputLimitExceeded
+				}
+			}
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// summarizeTestCases returns the status and exit code of the first test case
+// that did not succeed, or Successful when every case passed.
+func summarizeTestCases(results []TestCaseResult) (ExecutionStatus, int) {
+	for _, r := range results {
+		if r.Status != Successful {
+			return r.Status, r.ExitCode
+		}
+	}
+	return Successful, 0
+}
@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// ErrQueueFull is returned by Enqueue when no more jobs can be accepted.
+var ErrQueueFull = errors.New("execution queue is full")
+
+// Job is a single execution request. ID is the key of the model.Execution
+// record that tracks its progress. When RoomID is set, output is streamed to
+// that room while the job runs.
+type Job struct {
+	ID     string           `json:"id"`
+	RoomID string           `json:"roomId"`
+	Source model.SourceCode `json:"source"`
+}
+
+// JobQueue accepts jobs for asynchronous execution. LocalQueue runs them
+// in-process; a Faktory-backed queue only needs to push the job and register
+// ExecuteJob as its handler.
+type JobQueue interface {
+	Enqueue(job Job) error
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"log"
+	"sync"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+)
+
+// LocalQueue is a bounded in-process JobQueue served by a fixed pool of
+// goroutines.
+type LocalQueue struct {
+	jobs chan Job
+}
+
+var instance *LocalQueue
+var once sync.Once
+
+// NewLocalQueue starts concurrency workers that pull from a queue holding at
+// most size waiting jobs.
+func NewLocalQueue(concurrency, size int) *LocalQueue {
+	q := &LocalQueue{jobs: make(chan Job, size)}
+	for i := 0; i < concurrency; i++ {
+		go q.work()
+	}
+	return q
+}
+
+func GetInstance() *LocalQueue {
+	once.Do(func() {
+		conf := configs.GetInstance()
+		instance = NewLocalQueue(conf.WorkerConcurrency, conf.JobQueueSize)
+		log.Printf("Execution queue started with %d workers", conf.WorkerConcurrency)
+	})
+	return instance
+}
+
+// Enqueue never blocks; it returns ErrQueueFull when the queue is at capacity.
+func (q *LocalQueue) Enqueue(job Job) error {
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *LocalQueue) work() {
+	for job := range q.jobs {
+		ExecuteJob(job)
+	}
+}
@@ -9,12 +9,86 @@ const (
 	RuntimeError
 	RuntimeTimeout
 	Successful
+	Running
+	MemoryLimitExceeded
+	OutputLimitExceeded
+)
+
+// Finished reports whether the execution has reached a final status.
+func (s ExecutionStatus) Finished() bool {
+	return s != NotExecuted && s != Running
+}
+
+// Verdict is the judgement of a test case's output against its expected output.
+type Verdict string
+
+const (
+	NotJudged   Verdict = ""
+	Accepted    Verdict = "accepted"
+	WrongAnswer Verdict = "wrong_answer"
 )
 
 type Execution struct {
+	ID        string          `json:"id"`
 	Timestamp int64           `json:"timestamp"`
 	Status    ExecutionStatus `json:"status"`
 	ExitCode  int             `json:"exitCode"`
-	RunTime   int64           `json:"runTime"`
-	Output    string          `json:"output"`
+	// RunTime and CPUTime are the wall and CPU (user+sys) time of all test
+	// cases together, CompileTime the wall time of compiling, all in ms.
+	RunTime                int64  `json:"runTime"`
+	CPUTime                int64  `json:"cpuTime"`
+	CompileTime            int64  `json:"compileTime"`
+	Stdout                 string `json:"stdout"`
+	Stderr                 string `json:"stderr"`
+	CompileOutput          string `json:"compileOutput"`
+	StdoutTruncated        bool   `json:"stdoutTruncated"`
+	StderrTruncated        bool   `json:"stderrTruncated"`
+	CompileOutputTruncated bool   `json:"compileOutputTruncated"`
+	// PeakMemory is the sandbox's peak memory usage in KB, 0 when unknown.
+	PeakMemory int64            `json:"peakMemory"`
+	TestCases  []TestCaseResult `json:"testCases"`
+	// Verdict is Accepted when every test case with an expected output was
+	// accepted and WrongAnswer otherwise, including when one of them failed
+	// to run. It stays NotJudged when no test case has an expected output.
+	Verdict Verdict `json:"verdict"`
+	// Hidden marks a grading run over hidden tests; see Redacted.
+	Hidden bool `json:"hidden,omitempty"`
+	// Owner is the user who submitted the execution and RoomID the room it
+	// was submitted from, if any; only they may read it.
+	Owner  string `json:"owner,omitempty"`
+	RoomID string `json:"roomId,omitempty"`
+}
+
+// Redacted returns the execution without anything that could reveal the
+// test cases it ran. Only the statuses and verdicts are kept: the program
+// controls its output, exit codes, timings and memory, and could encode the
+// hidden inputs in any of them. The exit code only says whether it failed.
+func (e Execution) Redacted() Execution {
+	e.Stdout, e.Stderr = "", ""
+	e.StdoutTruncated, e.StderrTruncated = false, false
+	if e.ExitCode != 0 {
+		e.ExitCode = 1
+	}
+	e.RunTime, e.CPUTime, e.PeakMemory = 0, 0, 0
+	cases := make([]TestCaseResult, len(e.TestCases))
+	for i, tc := range e.TestCases {
+		cases[i] = TestCaseResult{Status: tc.Status, Verdict: tc.Verdict}
+	}
+	e.TestCases = cases
+	return e
+}
+
+// TestCaseResult is the outcome of running the program on one line of input.
+type TestCaseResult struct {
+	Input           string          `json:"input"`
+	Stdout          string          `json:"stdout"`
+	Stderr          string          `json:"stderr"`
+	ExitCode        int             `json:"exitCode"`
+	RunTime         int64           `json:"runTime"`
+	CPUTime         int64           `json:"cpuTime"`
+	Status          ExecutionStatus `json:"status"`
+	StdoutTruncated bool            `json:"stdoutTruncated"`
+	StderrTruncated bool            `json:"stderrTruncated"`
+	Expected        *string         `json:"expected,omitempty"`
+	Verdict         Verdict         `json:"verdict"`
 }
@@ -0,0 +1,28 @@
+package model
+
+// Problem is a question from the problem bank that a room can be bound to.
+type Problem struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Statement string `json:"statement"` // markdown
+	// StarterCode seeds the editor when a room is bound to the problem.
+	StarterCode map[ProgrammingLanguage]string `json:"starterCode"`
+	// SampleTests are shown to the candidate; HiddenTests are only used by
+	// grading and never leave the server.
+	SampleTests []ProblemTest `json:"sampleTests"`
+	HiddenTests []ProblemTest `json:"hiddenTests"`
+	TimeLimit   int           `json:"timeLimit"`   // per test case, in ms; 0 uses the executor default
+	MemoryLimit int           `json:"memoryLimit"` // in MB; 0 uses the executor default
+	Comparator  Comparator    `json:"comparator,omitempty"`
+	Tolerance   float64       `json:"tolerance,omitempty"`
+	Checker     string        `json:"checker,omitempty"`
+	CreatedAt   int64         `json:"createdAt"`
+	UpdatedAt   int64         `json:"updatedAt"`
+}
+
+// ProblemTest is one test case of a problem, in the same one-line input
+// format as SourceCode.Input.
+type ProblemTest struct {
+	Input    string `json:"input"`
+	Expected string `json:"expected"`
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RoomReview is what a room's interviewers write about the candidate. Only
+// interviewers ever see it.
+type RoomReview struct {
+	Notes     string    `json:"notes"` // markdown
+	Scorecard Scorecard `json:"scorecard"`
+	UpdatedAt int64     `json:"updatedAt"`
+}
+
+// Scorecard rates the candidate on a rubric.
+type Scorecard struct {
+	Ratings        []Rating       `json:"ratings"`
+	Recommendation Recommendation `json:"recommendation,omitempty"`
+}
+
+// Rating is the score of one rubric category.
+type Rating struct {
+	Category string `json:"category"`
+	Score    int    `json:"score"` // 1 to 4; 0 is not rated yet
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Recommendation is the interviewers' hiring decision.
+type Recommendation string
+
+const (
+	StrongNoHire Recommendation = "strong_no_hire"
+	NoHire       Recommendation = "no_hire"
+	Hire         Recommendation = "hire"
+	StrongHire   Recommendation = "strong_hire"
+)
+
+// Valid reports whether r is a known recommendation or empty.
+func (r Recommendation) Valid() bool {
+	switch r {
+	case "", StrongNoHire, NoHire, Hire, StrongHire:
+		return true
+	}
+	return false
+}
+
+// DefaultRubric is the categories a new scorecard rates.
+var DefaultRubric = []string{"Problem solving", "Coding", "Communication", "Testing"}
+
+const (
+	MaxNotesBytes       = 64 << 10
+	MaxRubricCategories = 20
+)
+
+// NewScorecard returns an unrated scorecard over DefaultRubric.
+func NewScorecard() Scorecard {
+	ratings := make([]Rating, len(DefaultRubric))
+	for i, category := range DefaultRubric {
+		ratings[i] = Rating{Category: category}
+	}
+	return Scorecard{Ratings: ratings}
+}
+
+// Validate checks the categories, scores and recommendation of s.
+func (s Scorecard) Validate() error {
+	if len(s.Ratings) > MaxRubricCategories {
+		return fmt.Errorf("a scorecard has at most %d categories", MaxRubricCategories)
+	}
+	seen := make(map[string]bool)
+	for _, rating := range s.Ratings {
+		if rating.Category == "" || len(rating.Category) > 64 {
+			return errors.New("categories must be 1 to 64 characters")
+		}
+		if seen[rating.Category] {
+			return fmt.Errorf("category %s appears twice", rating.Category)
+		}
+		seen[rating.Category] = true
+		if rating.Score < 0 || rating.Score > 4 {
+			return fmt.Errorf("the score of %s must be between 1 and 4", rating.Category)
+		}
+		if len(rating.Comment) > 2048 {
+			return fmt.Errorf("the comment on %s exceeds 2048 characters", rating.Category)
+		}
+	}
+	if !s.Recommendation.Valid() {
+		return fmt.Errorf("unknown recommendation: %s", s.Recommendation)
+	}
+	return nil
+}
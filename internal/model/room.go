@@ -0,0 +1,71 @@
+package model
+
+import "encoding/json"
+
+// Room is the metadata kept for every collaboration room.
+type Room struct {
+	ID        string `json:"id"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// RoomSnapshot is the full shared state of a room at a given revision.
+type RoomSnapshot struct {
+	Revision int `json:"revision"`
+	// Files holds the room's code by path. Entrypoint is the file that is
+	// run; empty means the language's source file.
+	Files      map[string]string `json:"files"`
+	Entrypoint string            `json:"entrypoint,omitempty"`
+	// Code is the single file of rooms saved before they had Files; it is
+	// only read, to migrate them.
+	Code     string              `json:"code,omitempty"`
+	Input    string              `json:"input"`
+	Output   string              `json:"output"`
+	Errors   string              `json:"errors"`
+	Language ProgrammingLanguage `json:"language"`
+	// ProblemID is the problem the room is bound to, if any.
+	ProblemID string `json:"problemId,omitempty"`
+	// Locked stops candidates editing. Kicked are the users removed from
+	// the room, who may not come back.
+	Locked bool     `json:"locked,omitempty"`
+	Kicked []string `json:"kicked,omitempty"`
+}
+
+// RoomDelta is one accepted change that moved the room from Revision-1 to
+// Revision: either Operation, an edit of the file at Path, or File, a change
+// to the file tree. Deltas saved before rooms had several files have no
+// Path and edit the room's only file.
+type RoomDelta struct {
+	Revision  int             `json:"revision"`
+	User      string          `json:"user"`
+	Path      string          `json:"path,omitempty"`
+	Operation json.RawMessage `json:"operation,omitempty"`
+	File      *FileChange     `json:"file,omitempty"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// FileAction is what a FileChange does.
+type FileAction string
+
+const (
+	CreateFile FileAction = "create" // adds Path with Content
+	DeleteFile FileAction = "delete" // removes Path
+	RenameFile FileAction = "rename" // moves Path to NewPath
+)
+
+// FileChange is a change to a room's file tree.
+type FileChange struct {
+	Action  FileAction `json:"action"`
+	Path    string     `json:"path"`
+	NewPath string     `json:"newPath,omitempty"`
+	Content string     `json:"content,omitempty"`
+}
+
+// RoomEvent is one entry of a room's recorded timeline: a message relayed by
+// the socket server, kept so the session can be reviewed and played back.
+type RoomEvent struct {
+	Type      string `json:"type"`
+	User      string `json:"user"`
+	Payload   string `json:"payload"`
+	Timestamp int64  `json:"timestamp"`
+}
@@ -1,5 +1,11 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type ProgrammingLanguage int
 
 const (
@@ -7,11 +13,115 @@ const (
 	Cpp
 	Java
 	Python3
+	Go
+	JavaScript
+	TypeScript
+	Rust
+	Kotlin
+)
+
+// Comparator decides whether a test case's output matches its expected output.
+type Comparator string
+
+const (
+	ExactComparator          Comparator = "exact"           // identical apart from trailing newlines
+	WhitespaceComparator     Comparator = "whitespace"      // same tokens, however they are spaced
+	UnorderedLinesComparator Comparator = "unordered_lines" // same lines in any order
+	FloatComparator          Comparator = "float"           // numbers equal within Tolerance
+	CustomComparator         Comparator = "custom"          // Checker decides
 )
 
+// Valid reports whether c is a known comparator. Empty means exact.
+func (c Comparator) Valid() bool {
+	switch c {
+	case "", ExactComparator, WhitespaceComparator, UnorderedLinesComparator, FloatComparator, CustomComparator:
+		return true
+	}
+	return false
+}
+
 type SourceCode struct {
 	Name     string              `json:"name" valid:"length(0|128)"`
-	Language ProgrammingLanguage `json:"language" valid:"range(0|4)"`
+	Language ProgrammingLanguage `json:"language"`
 	Content  string              `json:"content" valid:"length(0|8192)"`
 	Input    string              `json:"input" valid:"length(0|8192),optional"`
+	// Expected holds the expected output of each input line, in order. A
+	// null or missing entry leaves that test case unjudged.
+	Expected   []*string  `json:"expected,omitempty"`
+	Comparator Comparator `json:"comparator,omitempty"`
+	// Tolerance is the absolute or relative error FloatComparator allows;
+	// zero means 1e-6.
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// Checker is a shell script run as "sh checker.sh <input> <expected>
+	// <actual>" in the language's image; exit status 0 accepts the output.
+	Checker string `json:"checker,omitempty" valid:"length(0|8192),optional"`
+	// TimeLimit (per test case, in ms) and MemoryLimit (in MB) override the
+	// executor's defaults when positive.
+	TimeLimit   int `json:"timeLimit,omitempty"`
+	MemoryLimit int `json:"memoryLimit,omitempty"`
+	// Standard and Optimization select compiler flags for C and C++, e.g.
+	// "c11" or "c++20" and "O0" to "O3"; empty uses the language's defaults.
+	Standard     string `json:"standard,omitempty"`
+	Optimization string `json:"optimization,omitempty"`
+	// Interactive runs the program once with the room's "run_input" messages
+	// on its stdin instead of over Input's test cases; nothing is judged.
+	Interactive bool `json:"interactive,omitempty"`
+	// Files, when set, is a project of several files by path, relative to
+	// the working directory; Content is then ignored. Entrypoint is the file
+	// the language's commands build and run, by default its source file.
+	Files      map[string]string `json:"files,omitempty"`
+	Entrypoint string            `json:"entrypoint,omitempty"`
+}
+
+const (
+	MaxProjectFiles = 32
+	MaxProjectBytes = 64 << 10
+)
+
+// ValidateFiles checks Files against the project limits and Entrypoint
+// against Files.
+func (s SourceCode) ValidateFiles() error {
+	if len(s.Files) == 0 {
+		if s.Entrypoint != "" {
+			return errors.New("entrypoint needs files")
+		}
+		return nil
+	}
+	if len(s.Files) > MaxProjectFiles {
+		return fmt.Errorf("a project has at most %d files", MaxProjectFiles)
+	}
+	size := 0
+	for path, content := range s.Files {
+		if err := ValidateFilePath(path); err != nil {
+			return err
+		}
+		size += len(content)
+	}
+	if size > MaxProjectBytes {
+		return fmt.Errorf("files exceed %d bytes in total", MaxProjectBytes)
+	}
+	if _, ok := s.Files[s.Entrypoint]; s.Entrypoint != "" && !ok {
+		return fmt.Errorf("entrypoint %s is not one of the files", s.Entrypoint)
+	}
+	return nil
+}
+
+// ValidateFilePath rejects paths that could escape the working directory or
+// need quoting in a shell command: a path is made of slash-separated names
+// of letters, digits, '.', '_' and '-', none of them "." or "..".
+func ValidateFilePath(path string) error {
+	if path == "" || len(path) > 128 {
+		return fmt.Errorf("invalid file path %q: must be 1 to 128 characters", path)
+	}
+	for _, name := range strings.Split(path, "/") {
+		if name == "" || name == "." || name == ".." {
+			return fmt.Errorf("invalid file path %q", path)
+		}
+		for _, r := range name {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+				return fmt.Errorf("invalid file path %q: only letters, digits, '.', '_', '-' and '/' are allowed", path)
+			}
+		}
+	}
+	return nil
 }
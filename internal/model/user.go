@@ -0,0 +1,48 @@
+package model
+
+import "fmt"
+
+// User is an account that can log in: a local one with a password, or one
+// created on first login through the OIDC provider.
+type User struct {
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of a local account's password.
+	PasswordHash string `json:"passwordHash,omitempty"`
+	// Provider is "local" or "oidc". Subject is the provider's ID for an
+	// OIDC account, which must match on every login.
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// Role is what a participant is in a room.
+type Role string
+
+const (
+	Interviewer Role = "interviewer" // runs the session and invites others
+	Candidate   Role = "candidate"
+	Observer    Role = "observer" // watches, e.g. a shadowing interviewer
+)
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	switch r {
+	case Interviewer, Candidate, Observer:
+		return true
+	}
+	return false
+}
+
+// ValidateUsername accepts 1 to 64 letters, digits, '.', '_', '-' and '@',
+// so an e-mail address works as a username.
+func ValidateUsername(username string) error {
+	if username == "" || len(username) > 64 {
+		return fmt.Errorf("invalid username %q: must be 1 to 64 characters", username)
+	}
+	for _, r := range username {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' || r == '@') {
+			return fmt.Errorf("invalid username %q: only letters, digits, '.', '_', '-' and '@' are allowed", username)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Package ot implements operational transformation for plain-text documents.
+//
+// An Operation is a sequence of retain/insert/delete components that spans the
+// whole document it applies to. The JSON encoding matches ot.js so the browser
+// and the server can exchange operations verbatim:
+//
+//	[5, "abc", -2]  // retain 5, insert "abc", delete 2
+//
+// All lengths are counted in UTF-16 code units because that is how the
+// browser indexes strings.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// component is exactly one of retain, insert or delete.
+type component struct {
+	retain int
+	insert []uint16
+	delete int
+}
+
+func (c component) isRetain() bool { return c.retain > 0 }
+func (c component) isInsert() bool { return len(c.insert) > 0 }
+func (c component) isDelete() bool { return c.delete > 0 }
+
+// Operation is a single edit over a document of BaseLength code units that
+// produces a document of TargetLength code units.
+type Operation struct {
+	ops          []component
+	baseLength   int
+	targetLength int
+}
+
+// New returns an empty operation; chain Retain/Insert/Delete to build it.
+func New() *Operation {
+	return &Operation{}
+}
+
+// BaseLength is the length of the document the operation applies to.
+func (o *Operation) BaseLength() int { return o.baseLength }
+
+// TargetLength is the length of the document after the operation is applied.
+func (o *Operation) TargetLength() int { return o.targetLength }
+
+// IsNoop reports whether applying the operation leaves the document unchanged.
+func (o *Operation) IsNoop() bool {
+	return len(o.ops) == 0 || (len(o.ops) == 1 && o.ops[0].isRetain())
+}
+
+// Retain skips over n code units.
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.baseLength += n
+	o.targetLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isRetain() {
+		o.ops[last].retain += n
+		return o
+	}
+	o.ops = append(o.ops, component{retain: n})
+	return o
+}
+
+// Insert inserts text at the current position.
+func (o *Operation) Insert(text string) *Operation {
+	return o.insertUnits(utf16.Encode([]rune(text)))
+}
+
+func (o *Operation) insertUnits(units []uint16) *Operation {
+	if len(units) == 0 {
+		return o
+	}
+	o.targetLength += len(units)
+	last := len(o.ops) - 1
+	switch {
+	case last >= 0 && o.ops[last].isInsert():
+		o.ops[last].insert = append(o.ops[last].insert, units...)
+	case last >= 0 && o.ops[last].isDelete():
+		// Keep inserts before deletes so equivalent operations compare equal.
+		if last >= 1 && o.ops[last-1].isInsert() {
+			o.ops[last-1].insert = append(o.ops[last-1].insert, units...)
+		} else {
+			del := o.ops[last]
+			o.ops[last] = component{insert: append([]uint16(nil), units...)}
+			o.ops = append(o.ops, del)
+		}
+	default:
+		o.ops = append(o.ops, component{insert: append([]uint16(nil), units...)})
+	}
+	return o
+}
+
+// Delete removes n code units at the current position.
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.baseLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isDelete() {
+		o.ops[last].delete += n
+		return o
+	}
+	o.ops = append(o.ops, component{delete: n})
+	return o
+}
+
+// Apply returns doc with the operation applied.
+func (o *Operation) Apply(doc string) (string, error) {
+	src := utf16.Encode([]rune(doc))
+	if len(src) != o.baseLength {
+		return "", fmt.Errorf("operation base length %d does not match document length %d", o.baseLength, len(src))
+	}
+	out := make([]uint16, 0, o.targetLength)
+	i := 0
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			out = append(out, src[i:i+c.retain]...)
+			i += c.retain
+		case c.isInsert():
+			out = append(out, c.insert...)
+		case c.isDelete():
+			i += c.delete
+		}
+	}
+	return string(utf16.Decode(out)), nil
+}
+
+// Transform takes two operations a and b that apply to the same document and
+// returns a' and b' such that b'(a(doc)) == a'(b(doc)). When both insert at
+// the same position, a's text is placed first.
+func Transform(a, b *Operation) (*Operation, *Operation, error) {
+	if a.baseLength != b.baseLength {
+		return nil, nil, fmt.Errorf("cannot transform operations with base lengths %d and %d", a.baseLength, b.baseLength)
+	}
+	aPrime, bPrime := New(), New()
+	ia, ib := newCursor(a), newCursor(b)
+	for !ia.done() || !ib.done() {
+		if !ia.done() && ia.cur.isInsert() {
+			aPrime.insertUnits(ia.cur.insert)
+			bPrime.Retain(len(ia.cur.insert))
+			ia.next()
+			continue
+		}
+		if !ib.done() && ib.cur.isInsert() {
+			aPrime.Retain(len(ib.cur.insert))
+			bPrime.insertUnits(ib.cur.insert)
+			ib.next()
+			continue
+		}
+		if ia.done() {
+			return nil, nil, errors.New("first operation is too short")
+		}
+		if ib.done() {
+			return nil, nil, errors.New("first operation is too long")
+		}
+
+		n := min(ia.length(), ib.length())
+		switch {
+		case ia.cur.isRetain() && ib.cur.isRetain():
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+		case ia.cur.isDelete() && ib.cur.isRetain():
+			aPrime.Delete(n)
+		case ia.cur.isRetain() && ib.cur.isDelete():
+			bPrime.Delete(n)
+		}
+		// Both deleting the same span: nothing left for either side to do.
+		ia.consume(n)
+		ib.consume(n)
+	}
+	return aPrime, bPrime, nil
+}
+
+// cursor walks an operation's components, splitting retains and deletes as
+// they are partially consumed.
+type cursor struct {
+	ops []component
+	i   int
+	cur component
+}
+
+func newCursor(o *Operation) *cursor {
+	c := &cursor{ops: o.ops, i: -1}
+	c.next()
+	return c
+}
+
+func (c *cursor) done() bool { return c.i >= len(c.ops) }
+
+func (c *cursor) next() {
+	c.i++
+	if c.i < len(c.ops) {
+		c.cur = c.ops[c.i]
+	}
+}
+
+// length is the remaining length of a retain or delete component.
+func (c *cursor) length() int {
+	if c.cur.isRetain() {
+		return c.cur.retain
+	}
+	return c.cur.delete
+}
+
+func (c *cursor) consume(n int) {
+	if c.cur.isRetain() {
+		c.cur.retain -= n
+	} else {
+		c.cur.delete -= n
+	}
+	if c.length() == 0 {
+		c.next()
+	}
+}
+
+// MarshalJSON encodes the operation in ot.js form.
+func (o *Operation) MarshalJSON() ([]byte, error) {
+	out := make([]any, 0, len(o.ops))
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			out = append(out, c.retain)
+		case c.isInsert():
+			out = append(out, string(utf16.Decode(c.insert)))
+		case c.isDelete():
+			out = append(out, -c.delete)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an operation in ot.js form.
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*o = Operation{}
+	for _, r := range raw {
+		var n int
+		if err := json.Unmarshal(r, &n); err == nil {
+			switch {
+			case n > 0:
+				o.Retain(n)
+			case n < 0:
+				o.Delete(-n)
+			default:
+				return errors.New("operation component must not be zero")
+			}
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			return fmt.Errorf("invalid operation component %s", r)
+		}
+		o.Insert(s)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package ot_test
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/namnv2496/go-ide-pair/internal/ot"
+	"github.com/namnv2496/go-ide-pair/internal/ot/ottest"
+)
+
+func mustApply(t *testing.T, op *ot.Operation, doc string) string {
+	t.Helper()
+	out, err := op.Apply(doc)
+	if err != nil {
+		t.Fatalf("apply %s to %q: %v", encode(t, op), doc, err)
+	}
+	return out
+}
+
+func encode(t *testing.T, op *ot.Operation) string {
+	t.Helper()
+	data, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc  string
+		op   *ot.Operation
+		want string
+	}{
+		{"", ot.New().Insert("abc"), "abc"},
+		{"hello", ot.New().Retain(5).Insert(" world"), "hello world"},
+		{"hello world", ot.New().Retain(5).Delete(6), "hello"},
+		{"abc", ot.New().Delete(1).Insert("X").Retain(2), "Xbc"},
+		{"a😀b", ot.New().Retain(1).Delete(2).Retain(1), "ab"},
+	}
+	for _, tt := range tests {
+		if got := mustApply(t, tt.op, tt.doc); got != tt.want {
+			t.Errorf("apply %s to %q = %q, want %q", encode(t, tt.op), tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRejectsWrongLength(t *testing.T) {
+	if _, err := ot.New().Retain(3).Apply("ab"); err == nil {
+		t.Error("applying a 3 unit operation to a 2 unit document succeeded")
+	}
+}
+
+func TestTransformPutsFirstInsertFirst(t *testing.T) {
+	a, b := ot.New().Retain(1).Insert("A"), ot.New().Retain(1).Insert("B")
+	aPrime, bPrime, err := ot.Transform(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := mustApply(t, bPrime, mustApply(t, a, "x"))
+	right := mustApply(t, aPrime, mustApply(t, b, "x"))
+	if left != "xAB" || right != "xAB" {
+		t.Errorf("got %q and %q, want xAB", left, right)
+	}
+}
+
+func TestTransformRejectsDifferentBases(t *testing.T) {
+	if _, _, err := ot.Transform(ot.New().Retain(2), ot.New().Retain(3)); err == nil {
+		t.Error("transforming operations over different documents succeeded")
+	}
+}
+
+// TestTransformConverges checks b'(a(doc)) == a'(b(doc)) for random pairs.
+func TestTransformConverges(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 20000; i++ {
+		doc := ""
+		if rng.IntN(4) > 0 {
+			doc = ottest.RandomText(rng, 20)
+		}
+		a, b := ottest.RandomOp(rng, doc), ottest.RandomOp(rng, doc)
+		aPrime, bPrime, err := ot.Transform(a, b)
+		if err != nil {
+			t.Fatalf("transform %s, %s: %v", encode(t, a), encode(t, b), err)
+		}
+		left := mustApply(t, bPrime, mustApply(t, a, doc))
+		right := mustApply(t, aPrime, mustApply(t, b, doc))
+		if left != right {
+			t.Fatalf("doc %q, a %s, b %s: diverged to %q and %q", doc, encode(t, a), encode(t, b), left, right)
+		}
+	}
+}
+
+// transformSequence transforms op against every operation of seq in turn,
+// returning op past all of them and seq past op.
+func transformSequence(t *testing.T, op *ot.Operation, seq []*ot.Operation) (*ot.Operation, []*ot.Operation) {
+	t.Helper()
+	transformed := make([]*ot.Operation, len(seq))
+	for i, other := range seq {
+		var err error
+		if op, transformed[i], err = ot.Transform(op, other); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return op, transformed
+}
+
+// TestConcurrentStreamsConverge replays two sites editing concurrently, each
+// making several edits before seeing the other's, and checks that both end
+// on the same document once they exchange their streams.
+func TestConcurrentStreamsConverge(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 2000; i++ {
+		doc := ottest.RandomText(rng, 20)
+		streams := [2][]*ot.Operation{}
+		docs := [2]string{doc, doc}
+		for site := range streams {
+			for n := rng.IntN(6); n > 0; n-- {
+				op := ottest.RandomOp(rng, docs[site])
+				docs[site] = mustApply(t, op, docs[site])
+				streams[site] = append(streams[site], op)
+			}
+		}
+
+		// Site 0's stream, transformed past site 1's, lands on site 1, and
+		// the other way round.
+		pending := streams[1]
+		var forSite1 []*ot.Operation
+		for _, op := range streams[0] {
+			var moved *ot.Operation
+			moved, pending = transformSequence(t, op, pending)
+			forSite1 = append(forSite1, moved)
+		}
+		for _, op := range pending {
+			docs[0] = mustApply(t, op, docs[0])
+		}
+		for _, op := range forSite1 {
+			docs[1] = mustApply(t, op, docs[1])
+		}
+		if docs[0] != docs[1] {
+			t.Fatalf("doc %q: sites diverged to %q and %q", doc, docs[0], docs[1])
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	for i := 0; i < 1000; i++ {
+		doc := ottest.RandomText(rng, 20)
+		op := ottest.RandomOp(rng, doc)
+		var decoded ot.Operation
+		if err := json.Unmarshal([]byte(encode(t, op)), &decoded); err != nil {
+			t.Fatalf("decode %s: %v", encode(t, op), err)
+		}
+		if encode(t, &decoded) != encode(t, op) || mustApply(t, &decoded, doc) != mustApply(t, op, doc) {
+			t.Fatalf("%s decoded as %s", encode(t, op), encode(t, &decoded))
+		}
+	}
+}
+
+func TestUnmarshalRejectsZero(t *testing.T) {
+	var op ot.Operation
+	if err := json.Unmarshal([]byte(`[1, 0]`), &op); err == nil {
+		t.Error("decoding a zero component succeeded")
+	}
+}
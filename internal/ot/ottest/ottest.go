@@ -0,0 +1,45 @@
+// Package ottest generates random text and edits for testing code built on
+// package ot.
+package ottest
+
+import (
+	"math/rand/v2"
+	"unicode/utf16"
+
+	"github.com/namnv2496/go-ide-pair/internal/ot"
+)
+
+// alphabet is what random edits insert. It stays in the BMP so a random
+// retain or delete never splits a surrogate pair.
+var alphabet = []rune("ab xyzé世\n")
+
+// RandomText returns between 1 and max random characters.
+func RandomText(rng *rand.Rand, max int) string {
+	text := make([]rune, 1+rng.IntN(max))
+	for i := range text {
+		text[i] = alphabet[rng.IntN(len(alphabet))]
+	}
+	return string(text)
+}
+
+// RandomOp returns a random edit of doc.
+func RandomOp(rng *rand.Rand, doc string) *ot.Operation {
+	op := ot.New()
+	for remaining := len(utf16.Encode([]rune(doc))); remaining > 0; {
+		n := 1 + rng.IntN(min(remaining, 5))
+		switch rng.IntN(3) {
+		case 0:
+			op.Retain(n)
+			remaining -= n
+		case 1:
+			op.Delete(n)
+			remaining -= n
+		default:
+			op.Insert(RandomText(rng, 4))
+		}
+	}
+	if rng.IntN(2) == 0 {
+		op.Insert(RandomText(rng, 4))
+	}
+	return op
+}
@@ -0,0 +1,174 @@
+// Package report renders what a room's interviewers wrote for people outside
+// the room.
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// The PDF is written by hand: a few pages of text in the two standard
+// Helvetica fonts, which every reader has, so nothing is embedded. Those
+// fonts only cover Latin-1; other characters are printed as "?".
+
+const (
+	pageWidth    = 612 // US Letter, in points
+	pageHeight   = 792
+	margin       = 56
+	lineHeight   = 15
+	wrapColumn   = 85
+	linesPerPage = (pageHeight - 2*margin) / lineHeight
+)
+
+// line is one line of text on a page.
+type line struct {
+	text string
+	bold bool
+}
+
+var recommendationLabels = map[model.Recommendation]string{
+	model.StrongNoHire: "Strong no hire",
+	model.NoHire:       "No hire",
+	model.Hire:         "Hire",
+	model.StrongHire:   "Strong hire",
+}
+
+// ScorecardPDF renders the scorecard and notes of roomID as a PDF document.
+func ScorecardPDF(roomID string, review model.RoomReview) []byte {
+	var lines []line
+	add := func(text string, bold bool) {
+		for _, wrapped := range wrap(text, wrapColumn) {
+			lines = append(lines, line{wrapped, bold})
+		}
+	}
+
+	add("Interview scorecard", true)
+	add("Room "+roomID, false)
+	if review.UpdatedAt > 0 {
+		add("Last updated "+time.UnixMilli(review.UpdatedAt).UTC().Format("2006-01-02 15:04 MST"), false)
+	}
+	add("", false)
+
+	recommendation, ok := recommendationLabels[review.Scorecard.Recommendation]
+	if !ok {
+		recommendation = "None yet"
+	}
+	add("Recommendation: "+recommendation, true)
+	add("", false)
+
+	for _, rating := range review.Scorecard.Ratings {
+		score := "not rated"
+		if rating.Score > 0 {
+			score = fmt.Sprintf("%d / 4", rating.Score)
+		}
+		add(rating.Category+": "+score, true)
+		if rating.Comment != "" {
+			add(rating.Comment, false)
+		}
+		add("", false)
+	}
+
+	add("Notes", true)
+	if strings.TrimSpace(review.Notes) == "" {
+		add("No notes.", false)
+	}
+	for _, text := range strings.Split(review.Notes, "\n") {
+		add(text, false)
+	}
+
+	var pages [][]line
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	return render(append(pages, lines))
+}
+
+// wrap breaks text into lines of at most width characters, at spaces where
+// it can.
+func wrap(text string, width int) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\t", "    "), " \r")
+	var lines []string
+	for len([]rune(text)) > width {
+		runes := []rune(text)
+		cut := strings.LastIndex(string(runes[:width+1]), " ")
+		if cut <= 0 {
+			cut = len(string(runes[:width]))
+		}
+		lines = append(lines, strings.TrimRight(text[:cut], " "))
+		text = strings.TrimLeft(text[cut:], " ")
+	}
+	return append(lines, text)
+}
+
+// render writes pages as a PDF file. Objects 1 to 4 are the catalog, the page
+// tree and the two fonts; each page then takes an object for itself and one
+// for its content.
+func render(pages [][]line) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n%d TL\n%d %d Td\n", lineHeight, margin, pageHeight-margin)
+		for _, l := range page {
+			font := "/F1"
+			if l.bold {
+				font = "/F2"
+			}
+			fmt.Fprintf(&content, "%s 11 Tf\n(%s) Tj\nT*\n", font, escape(l.text))
+		}
+		content.WriteString("ET")
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// escape encodes s as the bytes of a PDF string in WinAnsi.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,307 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	roomsBucket     = []byte("rooms")
+	snapshotsBucket = []byte("snapshots")
+	// deltasBucket holds one nested bucket per room, keyed by big-endian revision.
+	deltasBucket = []byte("deltas")
+	// eventsBucket holds one nested bucket per room, keyed by big-endian sequence.
+	eventsBucket     = []byte("events")
+	executionsBucket = []byte("executions")
+	problemsBucket   = []byte("problems")
+	usersBucket      = []byte("users")
+	reviewsBucket    = []byte("reviews")
+)
+
+// BoltStore implements every store interface on top of a single BoltDB file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+var instance *BoltStore
+var once sync.Once
+
+// Open opens (or creates) the database at path.
+func Open(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{roomsBucket, snapshotsBucket, deltasBucket, eventsBucket, executionsBucket, problemsBucket, usersBucket, reviewsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func GetInstance() *BoltStore {
+	once.Do(func() {
+		path := configs.GetInstance().DBPath
+		s, err := Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open database %s: %v", path, err)
+		}
+		instance = s
+	})
+	return instance
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) SaveRoom(room model.Room) error {
+	return s.put(roomsBucket, room.ID, room)
+}
+
+func (s *BoltStore) ListRooms() ([]model.Room, error) {
+	var rooms []model.Room
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).ForEach(func(_, v []byte) error {
+			var room model.Room
+			if err := json.Unmarshal(v, &room); err != nil {
+				return err
+			}
+			rooms = append(rooms, room)
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+func (s *BoltStore) SaveSnapshot(roomID string, snapshot model.RoomSnapshot) error {
+	return s.put(snapshotsBucket, roomID, snapshot)
+}
+
+func (s *BoltStore) GetSnapshot(roomID string) (model.RoomSnapshot, error) {
+	var snapshot model.RoomSnapshot
+	err := s.get(snapshotsBucket, roomID, &snapshot)
+	return snapshot, err
+}
+
+func (s *BoltStore) WriteRooms(writes []RoomWrite) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, w := range writes {
+			if w.Room != nil {
+				if err := putJSON(tx.Bucket(roomsBucket), []byte(w.RoomID), w.Room); err != nil {
+					return err
+				}
+			}
+			if w.Snapshot != nil {
+				if err := putJSON(tx.Bucket(snapshotsBucket), []byte(w.RoomID), w.Snapshot); err != nil {
+					return err
+				}
+			}
+			if w.Delta != nil {
+				b, err := tx.Bucket(deltasBucket).CreateBucketIfNotExists([]byte(w.RoomID))
+				if err != nil {
+					return err
+				}
+				if err := putJSON(b, revisionKey(w.Delta.Revision), w.Delta); err != nil {
+					return err
+				}
+			}
+			if w.Review != nil {
+				if err := putJSON(tx.Bucket(reviewsBucket), []byte(w.RoomID), w.Review); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) ListDeltas(roomID string, afterRevision int) ([]model.RoomDelta, error) {
+	var deltas []model.RoomDelta
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deltasBucket).Bucket([]byte(roomID))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(revisionKey(afterRevision + 1)); k != nil; k, v = c.Next() {
+			var delta model.RoomDelta
+			if err := json.Unmarshal(v, &delta); err != nil {
+				return err
+			}
+			deltas = append(deltas, delta)
+		}
+		return nil
+	})
+	return deltas, err
+}
+
+func (s *BoltStore) AppendEvents(events []RecordedEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range events {
+			b, err := tx.Bucket(eventsBucket).CreateBucketIfNotExists([]byte(e.RoomID))
+			if err != nil {
+				return err
+			}
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := putJSON(b, revisionKey(int(seq)), e.Event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) ListEvents(roomID string) ([]model.RoomEvent, error) {
+	var events []model.RoomEvent
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket).Bucket([]byte(roomID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var event model.RoomEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	return events, err
+}
+
+func (s *BoltStore) SaveReview(roomID string, review model.RoomReview) error {
+	return s.put(reviewsBucket, roomID, review)
+}
+
+func (s *BoltStore) GetReview(roomID string) (model.RoomReview, error) {
+	var review model.RoomReview
+	err := s.get(reviewsBucket, roomID, &review)
+	return review, err
+}
+
+func (s *BoltStore) SaveExecution(execution model.Execution) error {
+	return s.put(executionsBucket, execution.ID, execution)
+}
+
+func (s *BoltStore) GetExecution(id string) (model.Execution, error) {
+	var execution model.Execution
+	err := s.get(executionsBucket, id, &execution)
+	return execution, err
+}
+
+func (s *BoltStore) SaveProblem(problem model.Problem) error {
+	return s.put(problemsBucket, problem.ID, problem)
+}
+
+func (s *BoltStore) GetProblem(id string) (model.Problem, error) {
+	var problem model.Problem
+	err := s.get(problemsBucket, id, &problem)
+	return problem, err
+}
+
+func (s *BoltStore) ListProblems() ([]model.Problem, error) {
+	var problems []model.Problem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(problemsBucket).ForEach(func(_, v []byte) error {
+			var problem model.Problem
+			if err := json.Unmarshal(v, &problem); err != nil {
+				return err
+			}
+			problems = append(problems, problem)
+			return nil
+		})
+	})
+	return problems, err
+}
+
+func (s *BoltStore) DeleteProblem(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(problemsBucket)
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("%s %q: %w", problemsBucket, id, ErrNotFound)
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) CreateUser(user model.User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		if b.Get([]byte(user.Username)) != nil {
+			return fmt.Errorf("%s %q: %w", usersBucket, user.Username, ErrExists)
+		}
+		return b.Put([]byte(user.Username), data)
+	})
+}
+
+func (s *BoltStore) GetUser(username string) (model.User, error) {
+	var user model.User
+	err := s.get(usersBucket, username, &user)
+	return user, err
+}
+
+func (s *BoltStore) put(bucket []byte, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+func putJSON(b *bolt.Bucket, key []byte, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+func (s *BoltStore) get(bucket []byte, key string, value any) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return fmt.Errorf("%s %q: %w", bucket, key, ErrNotFound)
+		}
+		return json.Unmarshal(data, value)
+	})
+}
+
+// revisionKey encodes rev (or any sequence number) so that byte order
+// matches numeric order.
+func revisionKey(rev int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(rev))
+	return key
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/namnv2496/go-ide-pair/internal/model"
+)
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned when creating a record whose key is taken.
+var ErrExists = errors.New("already exists")
+
+// RoomStore persists rooms so they survive a server restart.
+//
+// A room is rebuilt from its latest snapshot plus every delta with a higher
+// revision, so snapshots only need to be written every so often.
+type RoomStore interface {
+	SaveRoom(room model.Room) error
+	ListRooms() ([]model.Room, error)
+	SaveSnapshot(roomID string, snapshot model.RoomSnapshot) error
+	GetSnapshot(roomID string) (model.RoomSnapshot, error)
+	// WriteRooms applies writes in order, in a single transaction, so a
+	// batch of edits costs one sync.
+	WriteRooms(writes []RoomWrite) error
+	// ListDeltas returns the deltas of roomID with Revision > afterRevision,
+	// oldest first.
+	ListDeltas(roomID string, afterRevision int) ([]model.RoomDelta, error)
+	// AppendEvents appends events to their rooms' timelines, in order, in a
+	// single transaction.
+	AppendEvents(events []RecordedEvent) error
+	// ListEvents returns the recorded timeline of roomID, oldest first.
+	ListEvents(roomID string) ([]model.RoomEvent, error)
+	// SaveReview and GetReview keep the interviewers' notes and scorecard,
+	// apart from everything the room shares.
+	SaveReview(roomID string, review model.RoomReview) error
+	GetReview(roomID string) (model.RoomReview, error)
+	Close() error
+}
+
+// RoomWrite is a change to one persisted room: its next delta, its room
+// record and snapshot, or its review.
+type RoomWrite struct {
+	RoomID   string
+	Room     *model.Room
+	Snapshot *model.RoomSnapshot
+	Delta    *model.RoomDelta
+	Review   *model.RoomReview
+}
+
+// RecordedEvent is an event of one room's timeline.
+type RecordedEvent struct {
+	RoomID string
+	Event  model.RoomEvent
+}
+
+// ProblemStore persists the problem bank.
+type ProblemStore interface {
+	SaveProblem(problem model.Problem) error
+	GetProblem(id string) (model.Problem, error)
+	ListProblems() ([]model.Problem, error)
+	DeleteProblem(id string) error
+}
+
+// ExecutionStore persists the status and result of submitted executions.
+type ExecutionStore interface {
+	SaveExecution(execution model.Execution) error
+	GetExecution(id string) (model.Execution, error)
+}
+
+// UserStore persists accounts by username.
+type UserStore interface {
+	// CreateUser saves a new user, failing with ErrExists if the username
+	// is taken.
+	CreateUser(user model.User) error
+	GetUser(username string) (model.User, error)
+}
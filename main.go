@@ -5,21 +5,45 @@ import (
 	"net/http"
 
 	"github.com/namnv2496/go-ide-pair/api"
+	"github.com/namnv2496/go-ide-pair/internal/configs"
+	"github.com/namnv2496/go-ide-pair/internal/executor/remote"
 	"github.com/namnv2496/go-ide-pair/internal/executor/socket"
-	python3_job_executor "github.com/namnv2496/go-ide-pair/internal/executor/worker/python3_worker"
+	"github.com/namnv2496/go-ide-pair/internal/executor/terminal"
+	"github.com/namnv2496/go-ide-pair/internal/executor/worker"
+	"github.com/namnv2496/go-ide-pair/internal/store"
 )
 
 func main() {
+	if err := socket.LoadRooms(store.GetInstance()); err != nil {
+		log.Fatal("Failed to load rooms:", err)
+	}
 	go func() {
 		// Start WebSocket server
 		http.HandleFunc("/ws", socket.HandleConnections)
+		http.HandleFunc("/playback", socket.HandlePlayback)
+		http.HandleFunc("/terminal", terminal.HandleConnections)
 		log.Println("WebSocket server started on :8081")
 		if err := http.ListenAndServe(":8081", nil); err != nil {
 			log.Fatal("Error starting WebSocket server:", err)
 		}
 	}()
 	go socket.HandleMessages()
-	python3_job_executor.GetInstance()
+	if conf := configs.GetInstance(); conf.WorkerGRPCAddr != "" {
+		creds, err := remote.ServerCredentials(conf.WorkerTLSCert, conf.WorkerTLSKey)
+		if err != nil {
+			log.Fatal("Failed to load the worker gRPC certificate:", err)
+		}
+		if conf.WorkerTLSCert == "" {
+			log.Println("Warning: remote workers connect without TLS; keep the gRPC port on a private network")
+		}
+		go func() {
+			if err := remote.GetInstance().Serve(conf.WorkerGRPCAddr, creds); err != nil {
+				log.Fatal("Error starting the worker gRPC server:", err)
+			}
+		}()
+	}
+	go worker.WarmUp()
+	worker.GetInstance()
 	log.Println("http server started on :8080")
 	api.NewServer()
 }
@@ -0,0 +1,238 @@
+// Operational transformation for plain text — the browser half of
+// internal/ot. An operation is an array of components that spans the whole
+// document:  positive int = retain, string = insert, negative int = delete.
+// Lengths are UTF-16 code units, which is what JavaScript and Ace use.
+
+class TextOperation {
+    constructor() {
+        this.ops          = [];
+        this.baseLength   = 0;
+        this.targetLength = 0;
+    }
+
+    static isRetain(op) { return typeof op === 'number' && op > 0; }
+    static isInsert(op) { return typeof op === 'string'; }
+    static isDelete(op) { return typeof op === 'number' && op < 0; }
+
+    static fromJSON(ops) {
+        const o = new TextOperation();
+        for (const op of ops) {
+            if (TextOperation.isRetain(op))      o.retain(op);
+            else if (TextOperation.isInsert(op)) o.insert(op);
+            else if (TextOperation.isDelete(op)) o.delete(op);
+            else throw new Error('invalid operation component: ' + JSON.stringify(op));
+        }
+        return o;
+    }
+
+    toJSON() { return this.ops; }
+
+    retain(n) {
+        if (n === 0) return this;
+        this.baseLength   += n;
+        this.targetLength += n;
+        const last = this.ops.length - 1;
+        if (last >= 0 && TextOperation.isRetain(this.ops[last])) this.ops[last] += n;
+        else this.ops.push(n);
+        return this;
+    }
+
+    insert(str) {
+        if (str === '') return this;
+        this.targetLength += str.length;
+        const ops  = this.ops;
+        const last = ops.length - 1;
+        if (last >= 0 && TextOperation.isInsert(ops[last])) {
+            ops[last] += str;
+        } else if (last >= 0 && TextOperation.isDelete(ops[last])) {
+            // Keep inserts before deletes so equivalent operations compare equal.
+            if (last >= 1 && TextOperation.isInsert(ops[last - 1])) {
+                ops[last - 1] += str;
+            } else {
+                ops.push(ops[last]);
+                ops[last] = str;
+            }
+        } else {
+            ops.push(str);
+        }
+        return this;
+    }
+
+    delete(n) {
+        if (n === 0) return this;
+        if (n > 0) n = -n;
+        this.baseLength -= n;
+        const last = this.ops.length - 1;
+        if (last >= 0 && TextOperation.isDelete(this.ops[last])) this.ops[last] += n;
+        else this.ops.push(n);
+        return this;
+    }
+
+    // compose returns an operation with the same effect as this followed by other.
+    compose(other) {
+        if (this.targetLength !== other.baseLength) {
+            throw new Error('compose: base length of second operation must equal target length of first');
+        }
+        const result = new TextOperation();
+        const ops1 = this.ops, ops2 = other.ops;
+        let i1 = 0, i2 = 0;
+        let op1 = ops1[i1++], op2 = ops2[i2++];
+        while (op1 !== undefined || op2 !== undefined) {
+            if (TextOperation.isDelete(op1)) { result.delete(op1); op1 = ops1[i1++]; continue; }
+            if (TextOperation.isInsert(op2)) { result.insert(op2); op2 = ops2[i2++]; continue; }
+            if (op1 === undefined) throw new Error('compose: first operation is too short');
+            if (op2 === undefined) throw new Error('compose: first operation is too long');
+
+            if (TextOperation.isRetain(op1) && TextOperation.isRetain(op2)) {
+                if (op1 > op2)        { result.retain(op2); op1 -= op2; op2 = ops2[i2++]; }
+                else if (op1 === op2) { result.retain(op1); op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                  { result.retain(op1); op2 -= op1; op1 = ops1[i1++]; }
+            } else if (TextOperation.isInsert(op1) && TextOperation.isDelete(op2)) {
+                if (op1.length > -op2)        { op1 = op1.slice(-op2); op2 = ops2[i2++]; }
+                else if (op1.length === -op2) { op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                          { op2 += op1.length; op1 = ops1[i1++]; }
+            } else if (TextOperation.isInsert(op1) && TextOperation.isRetain(op2)) {
+                if (op1.length > op2)        { result.insert(op1.slice(0, op2)); op1 = op1.slice(op2); op2 = ops2[i2++]; }
+                else if (op1.length === op2) { result.insert(op1); op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                         { result.insert(op1); op2 -= op1.length; op1 = ops1[i1++]; }
+            } else if (TextOperation.isRetain(op1) && TextOperation.isDelete(op2)) {
+                if (op1 > -op2)        { result.delete(op2); op1 += op2; op2 = ops2[i2++]; }
+                else if (op1 === -op2) { result.delete(op2); op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                   { result.delete(op1); op2 += op1; op1 = ops1[i1++]; }
+            } else {
+                throw new Error('compose: incompatible operations');
+            }
+        }
+        return result;
+    }
+
+    // transform returns [a', b'] such that b'(a(doc)) === a'(b(doc)).
+    // When both insert at the same position, a's text is placed first —
+    // the server makes the same choice, so both sides converge.
+    static transform(a, b) {
+        if (a.baseLength !== b.baseLength) {
+            throw new Error('transform: both operations must have the same base length');
+        }
+        const aPrime = new TextOperation(), bPrime = new TextOperation();
+        const ops1 = a.ops, ops2 = b.ops;
+        let i1 = 0, i2 = 0;
+        let op1 = ops1[i1++], op2 = ops2[i2++];
+        while (op1 !== undefined || op2 !== undefined) {
+            if (TextOperation.isInsert(op1)) {
+                aPrime.insert(op1); bPrime.retain(op1.length); op1 = ops1[i1++]; continue;
+            }
+            if (TextOperation.isInsert(op2)) {
+                aPrime.retain(op2.length); bPrime.insert(op2); op2 = ops2[i2++]; continue;
+            }
+            if (op1 === undefined) throw new Error('transform: first operation is too short');
+            if (op2 === undefined) throw new Error('transform: first operation is too long');
+
+            let minl;
+            if (TextOperation.isRetain(op1) && TextOperation.isRetain(op2)) {
+                if (op1 > op2)        { minl = op2; op1 -= op2; op2 = ops2[i2++]; }
+                else if (op1 === op2) { minl = op2; op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                  { minl = op1; op2 -= op1; op1 = ops1[i1++]; }
+                aPrime.retain(minl); bPrime.retain(minl);
+            } else if (TextOperation.isDelete(op1) && TextOperation.isDelete(op2)) {
+                if (-op1 > -op2)      { op1 -= op2; op2 = ops2[i2++]; }
+                else if (op1 === op2) { op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                  { op2 -= op1; op1 = ops1[i1++]; }
+            } else if (TextOperation.isDelete(op1) && TextOperation.isRetain(op2)) {
+                if (-op1 > op2)        { minl = op2; op1 += op2; op2 = ops2[i2++]; }
+                else if (-op1 === op2) { minl = op2; op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                   { minl = -op1; op2 += op1; op1 = ops1[i1++]; }
+                aPrime.delete(minl);
+            } else if (TextOperation.isRetain(op1) && TextOperation.isDelete(op2)) {
+                if (op1 > -op2)        { minl = -op2; op1 += op2; op2 = ops2[i2++]; }
+                else if (op1 === -op2) { minl = op1; op1 = ops1[i1++]; op2 = ops2[i2++]; }
+                else                   { minl = op1; op2 += op1; op1 = ops1[i1++]; }
+                bPrime.delete(minl);
+            } else {
+                throw new Error('transform: incompatible operations');
+            }
+        }
+        return [aPrime, bPrime];
+    }
+
+    // fromAceDelta converts an Ace "change" delta into an operation. doc is the
+    // Ace document *after* the change, which is when Ace fires the event.
+    static fromAceDelta(delta, doc) {
+        const index = doc.positionToIndex(delta.start);
+        const text  = delta.lines.join(doc.getNewLineCharacter());
+        const after = doc.getValue().length;
+        const op    = new TextOperation().retain(index);
+        if (delta.action === 'insert') {
+            op.insert(text).retain(after - index - text.length);
+        } else {
+            op.delete(text.length).retain(after - index);
+        }
+        return op;
+    }
+
+    // applyToAce applies the operation to an Ace document in place.
+    applyToAce(doc) {
+        if (doc.getValue().length !== this.baseLength) {
+            throw new Error('applyToAce: operation does not match document length');
+        }
+        let index = 0;
+        for (const op of this.ops) {
+            if (TextOperation.isRetain(op)) {
+                index += op;
+            } else if (TextOperation.isInsert(op)) {
+                doc.insert(doc.indexToPosition(index), op);
+                index += op.length;
+            } else {
+                const start = doc.indexToPosition(index);
+                const end   = doc.indexToPosition(index - op);
+                doc.remove({ start, end });
+            }
+        }
+    }
+}
+
+// OTClient tracks which local operations the server has not yet acknowledged.
+// At most one operation is in flight; edits made meanwhile are composed into
+// a buffer and sent once the in-flight operation is acked.
+class OTClient {
+    constructor(revision, send, apply) {
+        this.revision    = revision;
+        this.outstanding = null;   // sent, awaiting ack
+        this.buffer      = null;   // not yet sent
+        this.send        = send;   // (revision, operation) => void
+        this.apply       = apply;  // (operation) => void
+    }
+
+    applyClient(op) {
+        if (this.outstanding === null) {
+            this.outstanding = op;
+            this.send(this.revision, op);
+        } else if (this.buffer === null) {
+            this.buffer = op;
+        } else {
+            this.buffer = this.buffer.compose(op);
+        }
+    }
+
+    applyServer(revision, op) {
+        this.revision = revision;
+        if (this.outstanding !== null) {
+            let opPrime;
+            [this.outstanding, opPrime] = TextOperation.transform(this.outstanding, op);
+            op = opPrime;
+            if (this.buffer !== null) {
+                [this.buffer, opPrime] = TextOperation.transform(this.buffer, op);
+                op = opPrime;
+            }
+        }
+        this.apply(op);
+    }
+
+    serverAck(revision) {
+        this.revision    = revision;
+        this.outstanding = this.buffer;
+        this.buffer      = null;
+        if (this.outstanding !== null) {
+            this.send(this.revision, this.outstanding);
+        }
+    }
+}